@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -9,12 +10,23 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App    `yaml:"app"`
-		HTTP   `yaml:"http"`
-		Log    `yaml:"logger"`
-		PG     `yaml:"postgres"`
-		Gemini `yaml:"gemini"`
-		OpenAI `yaml:"openai"`
+		App         `yaml:"app"`
+		HTTP        `yaml:"http"`
+		Log         `yaml:"logger"`
+		PG          `yaml:"postgres"`
+		Tenant      `yaml:"tenant"`
+		Gemini      `yaml:"gemini"`
+		OpenAI      `yaml:"openai"`
+		Search      `yaml:"search"`
+		Rerank      `yaml:"rerank"`
+		Embedding   `yaml:"embedding"`
+		Trash       `yaml:"trash"`
+		Bulk        `yaml:"bulk"`
+		Cursor      `yaml:"cursor"`
+		Import      `yaml:"import"`
+		Jobs        `yaml:"jobs"`
+		Dedup       `yaml:"dedup"`
+		Idempotency `yaml:"idempotency"`
 	}
 
 	// App -.
@@ -26,6 +38,8 @@ type (
 	// HTTP -.
 	HTTP struct {
 		Port string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		// MaxLimit caps the limit parameter of list and search endpoints.
+		MaxLimit int `env-default:"100" yaml:"max_limit" env:"HTTP_MAX_LIMIT"`
 	}
 
 	// Log -.
@@ -35,8 +49,18 @@ type (
 
 	// PG -.
 	PG struct {
-		PoolMax int    `env-required:"true" yaml:"pool_max" env:"PG_POOL_MAX"`
-		URL     string `env-required:"true"                 env:"PG_URL"`
+		PoolMax          int    `env-required:"true" yaml:"pool_max" env:"PG_POOL_MAX"`
+		URL              string `env-required:"true"                 env:"PG_URL"`
+		RowLevelSecurity bool   `env-default:"false" yaml:"row_level_security" env:"PG_ROW_LEVEL_SECURITY"`
+	}
+
+	// Tenant -.
+	Tenant struct {
+		DefaultID     string `env-default:"00000000-0000-0000-0000-000000000001" yaml:"default_id" env:"TENANT_DEFAULT_ID"`
+		RequireApiKey bool   `env-default:"false" yaml:"require_api_key" env:"TENANT_REQUIRE_API_KEY"`
+		// AllowHeader trusts the X-Tenant-ID header, for deployments behind a gateway that sets it.
+		AllowHeader bool   `env-default:"false" yaml:"allow_header" env:"TENANT_ALLOW_HEADER"`
+		AdminKey    string `yaml:"admin_key" env:"TENANT_ADMIN_KEY"`
 	}
 
 	// Gemini -.
@@ -46,7 +70,118 @@ type (
 
 	// OpenAI -.
 	OpenAI struct {
-		ApiKey string `env-required:"true" yaml:"api_key" env:"OPENAI_API_KEY"`
+		ApiKey         string `env-required:"true" yaml:"api_key"         env:"OPENAI_API_KEY"`
+		EmbeddingModel string `env-default:"text-embedding-3-small" yaml:"embedding_model" env:"OPENAI_EMBEDDING_MODEL"`
+	}
+
+	// Search -.
+	Search struct {
+		VectorWeight float64 `env-default:"0.7" yaml:"vector_weight" env:"SEARCH_VECTOR_WEIGHT"`
+		TextWeight   float64 `env-default:"0.3" yaml:"text_weight"   env:"SEARCH_TEXT_WEIGHT"`
+		// MaxResults is the most hits of a search that can be paged
+		// through and that facets are counted over.
+		MaxResults int `env-default:"1000" yaml:"max_results" env:"SEARCH_MAX_RESULTS"`
+	}
+
+	// Embedding -.
+	Embedding struct {
+		// Template is a text/template executed over entity.Movie; its output,
+		// with blank lines dropped, is the document sent to the embedder.
+		Template     string `env-default:"{{.NameEn}}\n{{.NameUz}}\n{{.NameRu}}\n{{range .Aliases}}{{.}}\n{{end}}" yaml:"template" env:"EMBEDDING_TEMPLATE"`
+		ReembedBatch int    `env-default:"100" yaml:"reembed_batch" env:"EMBEDDING_REEMBED_BATCH"`
+		// Workers is the number of goroutines generating the embeddings of
+		// created and changed movies. Retries use the Jobs settings.
+		Workers int `env-default:"1" yaml:"workers" env:"EMBEDDING_WORKERS"`
+	}
+
+	// Trash -.
+	Trash struct {
+		// Retention is how long deleted movies stay restorable; zero keeps them forever.
+		Retention     time.Duration `env-default:"720h" yaml:"retention" env:"TRASH_RETENTION"`
+		PurgeInterval time.Duration `env-default:"1h" yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL"`
+	}
+
+	// Bulk -.
+	Bulk struct {
+		// MaxRows is the most movies one bulk update may change; larger
+		// updates have to be split by narrower filters.
+		MaxRows int `env-default:"1000" yaml:"max_rows" env:"BULK_MAX_ROWS"`
+	}
+
+	// Cursor -.
+	Cursor struct {
+		// Secret signs pagination cursors. When empty a random secret is used,
+		// so cursors do not survive a restart and are not shared between instances.
+		Secret string `yaml:"secret" env:"CURSOR_SECRET"`
+	}
+
+	// Import -.
+	Import struct {
+		// BatchSize is the number of lines validated, embedded and written together.
+		BatchSize int `env-default:"500" yaml:"batch_size" env:"IMPORT_BATCH_SIZE"`
+	}
+
+	// Jobs -.
+	Jobs struct {
+		Workers      int           `env-default:"2" yaml:"workers" env:"JOBS_WORKERS"`
+		PollInterval time.Duration `env-default:"1s" yaml:"poll_interval" env:"JOBS_POLL_INTERVAL"`
+		// Lease is how long a running job stays locked without a heartbeat
+		// before another worker may take it over.
+		Lease       time.Duration `env-default:"1m" yaml:"lease" env:"JOBS_LEASE"`
+		MaxAttempts int           `env-default:"3" yaml:"max_attempts" env:"JOBS_MAX_ATTEMPTS"`
+		// Failed attempts are retried after BackoffBase, doubled for every
+		// further attempt up to BackoffMax.
+		BackoffBase time.Duration `env-default:"10s" yaml:"backoff_base" env:"JOBS_BACKOFF_BASE"`
+		BackoffMax  time.Duration `env-default:"10m" yaml:"backoff_max" env:"JOBS_BACKOFF_MAX"`
+		// MaxInput is the largest upload in bytes a job may be queued with.
+		MaxInput int `env-default:"33554432" yaml:"max_input" env:"JOBS_MAX_INPUT"`
+	}
+
+	// Dedup -.
+	Dedup struct {
+		// Mode is what creating a movie that looks like a stored one does:
+		// off skips the check, warn creates it and lists the look-alikes, and
+		// reject answers 409 with them unless the request is forced.
+		Mode string `env-default:"warn" yaml:"mode" env:"DEDUP_MODE"`
+		// Movies whose names in the same language have at least this trigram
+		// similarity, or whose embeddings have at least this cosine
+		// similarity, are likely duplicates. Zero turns a check off. Names
+		// are checked on create; embeddings only exist later, so the
+		// embedding workers flag vector look-alikes for the duplicate report.
+		NameSimilarity   float64 `env-default:"0.6"  yaml:"name_similarity"   env:"DEDUP_NAME_SIMILARITY"`
+		VectorSimilarity float64 `env-default:"0.95" yaml:"vector_similarity" env:"DEDUP_VECTOR_SIMILARITY"`
+		// Candidates is the most look-alikes reported per movie.
+		Candidates int `env-default:"5" yaml:"candidates" env:"DEDUP_CANDIDATES"`
+		// MaxPairs is the most pairs of each check the duplicate report
+		// clusters; the most similar are kept.
+		MaxPairs int `env-default:"10000" yaml:"max_pairs" env:"DEDUP_MAX_PAIRS"`
+	}
+
+	// Idempotency -.
+	Idempotency struct {
+		// TTL is how long the response to a write request with an
+		// Idempotency-Key header is replayed to retries; zero ignores the header.
+		TTL time.Duration `env-default:"24h" yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+		// Lock is how long a key stays claimed by a request that did not
+		// finish, e.g. because its instance stopped, before a retry runs again.
+		Lock          time.Duration `env-default:"5m" yaml:"lock" env:"IDEMPOTENCY_LOCK"`
+		PurgeInterval time.Duration `env-default:"1h" yaml:"purge_interval" env:"IDEMPOTENCY_PURGE_INTERVAL"`
+		// MaxRequest is the largest request body in bytes accepted with a key,
+		// and MaxResponse the largest response stored; larger responses are
+		// not replayed.
+		MaxRequest  int `env-default:"33554432" yaml:"max_request"  env:"IDEMPOTENCY_MAX_REQUEST"`
+		MaxResponse int `env-default:"1048576"  yaml:"max_response" env:"IDEMPOTENCY_MAX_RESPONSE"`
+	}
+
+	// Rerank -.
+	Rerank struct {
+		Enabled  bool          `env-default:"false"       yaml:"enabled"  env:"RERANK_ENABLED"`
+		Provider string        `env-default:"openai"      yaml:"provider" env:"RERANK_PROVIDER"` // openai, local
+		Model    string        `env-default:"gpt-4o-mini" yaml:"model"    env:"RERANK_MODEL"`
+		BaseURL  string        `yaml:"base_url" env:"RERANK_BASE_URL"`
+		ApiKey   string        `yaml:"api_key"  env:"RERANK_API_KEY"`
+		TopN     int           `env-default:"20"          yaml:"top_n"    env:"RERANK_TOP_N"`
+		Timeout  time.Duration `env-default:"2s"          yaml:"timeout"  env:"RERANK_TIMEOUT"`
 	}
 )
 
@@ -17,6 +17,9 @@ var (
 	ErrorConflict       = "CONFLICT"
 	ErrorBadRequest     = "BAD_REQUEST"
 	ErrorDuplicateKey   = "DUPLICATE_KEY"
+	ErrorQuotaExceeded  = "QUOTA_EXCEEDED"
+	ErrorTooManyRequest = "TOO_MANY_REQUESTS"
+	ErrorPrecondition   = "PRECONDITION_FAILED"
 )
 
 var (
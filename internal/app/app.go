@@ -2,19 +2,26 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/abdulazizax/ai-embedding/config"
 	v1 "github.com/abdulazizax/ai-embedding/internal/controller/http/v1"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
 	"github.com/abdulazizax/ai-embedding/internal/usecase"
 	"github.com/abdulazizax/ai-embedding/pkg/httpserver"
 	"github.com/abdulazizax/ai-embedding/pkg/logger"
 	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/rerank"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/jackc/pgx/v4"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -23,7 +30,14 @@ func Run(cfg *config.Config) {
 	l := logger.New(cfg.Log.Level)
 
 	// Repository
-	pg, err := postgres.New(cfg.PG.URL, postgres.MaxPoolSize(cfg.PG.PoolMax))
+	pgOptions := []postgres.Option{postgres.MaxPoolSize(cfg.PG.PoolMax)}
+	if cfg.PG.RowLevelSecurity {
+		pgOptions = append(pgOptions, postgres.BeforeAcquire(setTenantSetting))
+	} else {
+		pgOptions = append(pgOptions, postgres.AfterConnect(bypassTenantSetting))
+	}
+
+	pg, err := postgres.New(cfg.PG.URL, pgOptions...)
 	if err != nil {
 		l.Fatal(fmt.Errorf("app - Run - postgres.New: %w", err))
 	}
@@ -31,8 +45,74 @@ func Run(cfg *config.Config) {
 
 	openaiClient := openai.NewClient(cfg.OpenAI.ApiKey)
 
+	reranker, err := newReranker(cfg)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - newReranker: %w", err))
+	}
+
 	// Use case
-	useCase := usecase.New(openaiClient, pg, cfg, l)
+	useCase := usecase.New(openaiClient, reranker, pg, cfg, l)
+
+	// Background jobs run for all tenants until shutdown; running jobs are
+	// handed back to the queue then.
+	jobsCtx, stopJobs := context.WithCancel(tenant.WithAll(context.Background()))
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		useCase.Worker.Run(jobsCtx)
+	}()
+
+	// Embeddings of created and changed movies are generated from the outbox.
+	for i := 0; i < cfg.Embedding.Workers; i++ {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			processEmbeddings(jobsCtx, useCase.MovieRepo, cfg.Jobs.PollInterval, l)
+		}()
+	}
+
+	// Movies embedded with an older document template are refreshed in the
+	// background. The unique key keeps instances starting together from
+	// queueing the work twice.
+	_, err = useCase.JobRepo.Enqueue(tenant.WithAll(context.Background()), entity.Job{
+		Kind:      entity.JobMovieReembed,
+		UniqueKey: entity.JobMovieReembed,
+	})
+	if err != nil {
+		l.Error(fmt.Errorf("app - Run - Enqueue %s: %w", entity.JobMovieReembed, err))
+	}
+
+	// Movies deleted longer than the retention period ago are purged from the trash.
+	if cfg.Trash.Retention > 0 && cfg.Trash.PurgeInterval > 0 {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			runEvery(jobsCtx, cfg.Trash.PurgeInterval, func(ctx context.Context) {
+				n, err := useCase.MovieRepo.PurgeTrash(ctx, cfg.Trash.Retention)
+				if err != nil && ctx.Err() == nil {
+					l.Error(fmt.Errorf("app - Run - PurgeTrash: %w", err))
+				}
+				if n > 0 {
+					l.Info("app - Run - PurgeTrash: %d movies purged", n)
+				}
+			})
+		}()
+	}
+
+	// Expired idempotency keys are deleted to keep their table small.
+	if cfg.Idempotency.TTL > 0 && cfg.Idempotency.PurgeInterval > 0 {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			runEvery(jobsCtx, cfg.Idempotency.PurgeInterval, func(ctx context.Context) {
+				_, err := useCase.IdempotencyRepo.PurgeExpired(ctx)
+				if err != nil && ctx.Err() == nil {
+					l.Error(fmt.Errorf("app - Run - PurgeExpired: %w", err))
+				}
+			})
+		}()
+	}
 
 	// HTTP Server
 	handler := gin.New()
@@ -58,4 +138,95 @@ func Run(cfg *config.Config) {
 	if err != nil {
 		l.Error(fmt.Errorf("app - Run - httpServer.Shutdown: %w", err))
 	}
+
+	stopJobs()
+	jobs.Wait()
+}
+
+// processEmbeddings drains the embedding outbox until ctx is cancelled,
+// waiting for interval whenever it is empty or a batch failed.
+func processEmbeddings(ctx context.Context, movies usecase.MovieRepoI, interval time.Duration, l *logger.Logger) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for ctx.Err() == nil {
+		n, err := movies.ProcessEmbeddings(ctx)
+		if err != nil && ctx.Err() == nil {
+			l.Error(fmt.Errorf("app - processEmbeddings - ProcessEmbeddings: %w", err))
+		}
+
+		if n == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// runEvery calls fn right away and then every interval until ctx is
+// cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setTenantSetting stores the tenant of the acquiring request in the
+// app.tenant_id setting the row-level security policies check. Connections
+// acquired without a tenant see no rows, unless they are acquired for all
+// tenants, e.g. by background jobs, which sets app.bypass_rls.
+func setTenantSetting(ctx context.Context, conn *pgx.Conn) bool {
+	bypass := "off"
+	if tenant.ID(ctx) == "" && tenant.All(ctx) {
+		bypass = "on"
+	}
+
+	_, err := conn.Exec(ctx, "SELECT set_config('app.tenant_id', $1, false), set_config('app.bypass_rls', $2, false)",
+		tenant.ID(ctx), bypass)
+	return err == nil
+}
+
+// bypassTenantSetting lets every connection past the row-level security
+// policies when PG_ROW_LEVEL_SECURITY is disabled; the tenant_id predicates
+// of the queries still apply.
+func bypassTenantSetting(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, "SELECT set_config('app.bypass_rls', 'on', false)")
+	return err
+}
+
+// newReranker builds the search reranker selected in config, or nil when re-ranking is disabled.
+func newReranker(cfg *config.Config) (rerank.Interface, error) {
+	if !cfg.Rerank.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Rerank.Provider {
+	case "local":
+		return rerank.NewLocal(), nil
+	case "openai":
+		apiKey := cfg.Rerank.ApiKey
+		if apiKey == "" {
+			apiKey = cfg.OpenAI.ApiKey
+		}
+
+		clientConfig := openai.DefaultConfig(apiKey)
+		if cfg.Rerank.BaseURL != "" {
+			clientConfig.BaseURL = cfg.Rerank.BaseURL
+		}
+
+		return rerank.NewOpenAI(openai.NewClientWithConfig(clientConfig), cfg.Rerank.Model), nil
+	default:
+		return nil, fmt.Errorf("unknown rerank provider %q", cfg.Rerank.Provider)
+	}
 }
@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateCollection godoc
+// @Router /collections [post]
+// @Summary Create a new collection
+// @Description Create a collection with its fields, embedded fields, vector dimension and distance metric
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param collection body entity.Collection true "Collection object"
+// @Success 201 {object} entity.Collection
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateCollection(ctx *gin.Context) {
+	var (
+		body entity.Collection
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	collection, err := h.UseCase.CollectionRepo.Create(ctx, body)
+	if h.HandleDbError(ctx, err, "Error creating collection") {
+		return
+	}
+
+	ctx.JSON(201, collection)
+}
+
+// GetCollection godoc
+// @Router /collections/{name} [get]
+// @Summary Get a collection by name
+// @Description Get a collection by name
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Success 200 {object} entity.Collection
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetCollection(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("name")
+
+	collection, err := h.UseCase.CollectionRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting collection") {
+		return
+	}
+
+	ctx.JSON(200, collection)
+}
+
+// GetCollections godoc
+// @Router /collections [get]
+// @Summary Get a list of collections
+// @Description Get a list of collections
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.CollectionList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetCollections(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "name",
+		Order:  "asc",
+	})
+
+	collections, err := h.UseCase.CollectionRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting collections") {
+		return
+	}
+
+	ctx.JSON(200, collections)
+}
+
+// DeleteCollection godoc
+// @Router /collections/{name} [delete]
+// @Summary Delete a collection
+// @Description Delete a collection together with all of its documents
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteCollection(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("name")
+
+	err := h.UseCase.CollectionRepo.Delete(ctx, req)
+	if h.HandleDbError(ctx, err, "Error deleting collection") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Collection deleted successfully",
+	})
+}
+
+// CreateDocument godoc
+// @Router /collections/{name}/documents [post]
+// @Summary Create a new document
+// @Description Create a document in a collection; its embedded fields are embedded on write
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param document body entity.Document true "Document object"
+// @Success 201 {object} entity.Document
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateDocument(ctx *gin.Context) {
+	var (
+		body entity.Document
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	document, err := h.UseCase.CollectionRepo.CreateDocument(ctx, ctx.Param("name"), body)
+	if h.HandleDbError(ctx, err, "Error creating document") {
+		return
+	}
+
+	ctx.JSON(201, document)
+}
+
+// GetDocument godoc
+// @Router /collections/{name}/documents/{id} [get]
+// @Summary Get a document by ID
+// @Description Get a document by ID
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Success 200 {object} entity.Document
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetDocument(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	document, err := h.UseCase.CollectionRepo.GetDocument(ctx, ctx.Param("name"), req)
+	if h.HandleDbError(ctx, err, "Error getting document") {
+		return
+	}
+
+	ctx.JSON(200, document)
+}
+
+// GetDocuments godoc
+// @Router /collections/{name}/documents [get]
+// @Summary Get a list of documents
+// @Description Get a list of documents in a collection
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.DocumentList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetDocuments(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+
+	documents, err := h.UseCase.CollectionRepo.GetDocuments(ctx, ctx.Param("name"), req)
+	if h.HandleDbError(ctx, err, "Error getting documents") {
+		return
+	}
+
+	ctx.JSON(200, documents)
+}
+
+// UpdateDocument godoc
+// @Router /collections/{name}/documents/{id} [put]
+// @Summary Update a document
+// @Description Replace all fields of a document and embed it again
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param document body entity.Document true "Document object"
+// @Success 200 {object} entity.Document
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateDocument(ctx *gin.Context) {
+	var (
+		body entity.Document
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	body.ID = ctx.Param("id")
+
+	document, err := h.UseCase.CollectionRepo.UpdateDocument(ctx, ctx.Param("name"), body)
+	if h.HandleDbError(ctx, err, "Error updating document") {
+		return
+	}
+
+	ctx.JSON(200, document)
+}
+
+// DeleteDocument godoc
+// @Router /collections/{name}/documents/{id} [delete]
+// @Summary Delete a document
+// @Description Delete a document
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) DeleteDocument(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	err := h.UseCase.CollectionRepo.DeleteDocument(ctx, ctx.Param("name"), req)
+	if h.HandleDbError(ctx, err, "Error deleting document") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Document deleted successfully",
+	})
+}
+
+// SearchDocuments godoc
+// @Router /collections/{name}/search [get]
+// @Summary Search documents
+// @Description Get the documents of a collection nearest to the search query
+// @Security BearerAuth
+// @Tags collection
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Collection name"
+// @Param search query string true "Search query"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.DocumentList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) SearchDocuments(ctx *gin.Context) {
+	var (
+		req entity.DocumentSearchRequest
+	)
+
+	req.Collection = ctx.Param("name")
+	req.Query = ctx.Query("search")
+	if req.Query == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Query parameter search is required", 400)
+		return
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+	req.Limit = limit
+
+	documents, err := h.UseCase.CollectionRepo.SearchDocuments(ctx, req)
+	if h.HandleDbError(ctx, err, "Error searching documents") {
+		return
+	}
+
+	ctx.JSON(200, documents)
+}
@@ -12,6 +12,28 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// errorStatuses maps the codes repositories put in front of their error
+// messages to the HTTP status reported for them.
+var errorStatuses = []struct {
+	code   string
+	status int
+}{
+	{config.ErrorBadRequest, http.StatusBadRequest},
+	{config.ErrorQuotaExceeded, http.StatusTooManyRequests},
+	{config.ErrorPrecondition, http.StatusPreconditionFailed},
+	{config.ErrorConflict, http.StatusConflict},
+}
+
+func errorCode(err error) (string, int, bool) {
+	for _, e := range errorStatuses {
+		if strings.Contains(err.Error(), e.code) {
+			return e.code, e.status, true
+		}
+	}
+
+	return "", 0, false
+}
+
 func (h Handler) HandleDbError(c *gin.Context, err error, message string) bool {
 	if err == nil {
 		return false
@@ -63,11 +85,13 @@ func (h Handler) HandleDbError(c *gin.Context, err error, message string) bool {
 			}
 		}
 	default:
-		if strings.Contains(err.Error(), "BAD_REQUEST") {
+		if code, status, ok := errorCode(err); ok {
+			msg := err.Error()
 			errorResponse = entity.ErrorResponse{
-				Message: strings.TrimPrefix(err.Error(), "BAD_REQUEST"),
-				Code:    config.ErrorBadRequest,
+				Message: msg[strings.Index(msg, code)+len(code):],
+				Code:    code,
 			}
+			statusCode = status
 		} else {
 			// General PostgreSQL error
 			errorResponse = entity.ErrorResponse{
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/gin-gonic/gin"
+)
+
+// movieETag is the entity tag of a movie at the given version.
+func movieETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion returns the version required by the If-Match header, or 0
+// when the header is absent or "*". Only a single entity tag is supported.
+// If-Match compares strongly, so a weak tag never matches.
+func ifMatchVersion(ctx *gin.Context) (int, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if strings.Contains(header, ",") {
+		return 0, fmt.Errorf(config.ErrorBadRequest + "If-Match supports a single entity tag")
+	}
+
+	if strings.HasPrefix(header, "W/") {
+		return 0, fmt.Errorf(config.ErrorPrecondition + "weak entity tags never match in If-Match")
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf(config.ErrorBadRequest + "invalid entity tag in If-Match")
+	}
+
+	return version, nil
+}
+
+// notModified sets the ETag header and, when If-None-Match matches it,
+// answers 304 Not Modified and reports true.
+func notModified(ctx *gin.Context, etag string) bool {
+	ctx.Header("ETag", etag)
+
+	for _, tag := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			ctx.Status(304)
+			return true
+		}
+	}
+
+	return false
+}
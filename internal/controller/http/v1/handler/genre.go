@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateGenre godoc
+// @Router /genre [post]
+// @Summary Create a new genre
+// @Description Create a new genre
+// @Security BearerAuth
+// @Tags genre
+// @Accept  json
+// @Produce  json
+// @Param genre body entity.Genre true "Genre object"
+// @Success 201 {object} entity.Genre
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateGenre(ctx *gin.Context) {
+	var (
+		body entity.Genre
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Slug) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	genre, err := h.UseCase.GenreRepo.Create(ctx, body)
+	if h.HandleDbError(ctx, err, "Error creating genre") {
+		return
+	}
+
+	ctx.JSON(201, genre)
+}
+
+// GetGenre godoc
+// @Router /genre/{id} [get]
+// @Summary Get a genre by ID
+// @Description Get a genre by ID
+// @Security BearerAuth
+// @Tags genre
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Genre ID"
+// @Success 200 {object} entity.Genre
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetGenre(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	genre, err := h.UseCase.GenreRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting genre") {
+		return
+	}
+
+	ctx.JSON(200, genre)
+}
+
+// GetGenres godoc
+// @Router /genre/list [get]
+// @Summary Get a list of genres
+// @Description Get a list of genres
+// @Security BearerAuth
+// @Tags genre
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.GenreList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetGenres(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "slug",
+		Order:  "asc",
+	})
+
+	genres, err := h.UseCase.GenreRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting genres") {
+		return
+	}
+
+	ctx.JSON(200, genres)
+}
+
+// UpdateGenre godoc
+// @Router /genre [put]
+// @Summary Update a genre
+// @Description Update a genre
+// @Security BearerAuth
+// @Tags genre
+// @Accept  json
+// @Produce  json
+// @Param genre body entity.Genre true "Genre object"
+// @Success 200 {object} entity.Genre
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateGenre(ctx *gin.Context) {
+	var (
+		body entity.Genre
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.ID == "" || strings.TrimSpace(body.Slug) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	genre, err := h.UseCase.GenreRepo.Update(ctx, body)
+	if h.HandleDbError(ctx, err, "Error updating genre") {
+		return
+	}
+
+	ctx.JSON(200, genre)
+}
+
+// DeleteGenre godoc
+// @Router /genre/{id} [delete]
+// @Summary Delete a genre
+// @Description Delete a genre and remove it from the movies it is linked to
+// @Security BearerAuth
+// @Tags genre
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Genre ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteGenre(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	err := h.UseCase.GenreRepo.Delete(ctx, req)
+	if h.HandleDbError(ctx, err, "Error deleting genre") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Genre deleted successfully",
+	})
+}
@@ -10,6 +10,9 @@ type Handler struct {
 	Logger  *logger.Logger
 	Config  *config.Config
 	UseCase *usecase.UseCase
+
+	tenants *tenantCache
+	limiter *rateLimiter
 }
 
 func NewHandler(l *logger.Logger, c *config.Config, useCase *usecase.UseCase) *Handler {
@@ -17,5 +20,7 @@ func NewHandler(l *logger.Logger, c *config.Config, useCase *usecase.UseCase) *H
 		Logger:  l,
 		Config:  c,
 		UseCase: useCase,
+		tenants: newTenantCache(),
+		limiter: newRateLimiter(),
 	}
 }
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+)
+
+// idempotentHeaders are the response headers stored and replayed with a
+// response.
+var idempotentHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency answers write requests carrying an Idempotency-Key header only
+// once: the response is stored for Idempotency.TTL and replayed to requests
+// repeating the key. A key sent with a different method, URL or body is
+// rejected with 422, and one whose first request is still running with 409.
+// Server errors and rate limits are not stored, so retrying them runs the
+// request again. It has to run after ResolveTenant, as keys are per tenant.
+func (h *Handler) Idempotency() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			key = ""
+		}
+		if key == "" || h.Config.Idempotency.TTL <= 0 {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			h.abortWithError(ctx, config.ErrorBadRequest, "Idempotency-Key may have at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := readBody(ctx.Request, h.Config.Idempotency.MaxRequest)
+		if err != nil {
+			h.abortWithError(ctx, config.ErrorBadRequest, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		hash := requestHash(ctx.Request, body)
+
+		stored, claimed, err := h.UseCase.IdempotencyRepo.Claim(ctx, key, hash, h.Config.Idempotency.Lock)
+		if h.HandleDbError(ctx, err, "Error claiming idempotency key") {
+			ctx.Abort()
+			return
+		}
+
+		if !claimed {
+			switch {
+			case stored.RequestHash != hash:
+				h.abortWithError(ctx, config.ErrorConflict, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+			case stored.Status == 0:
+				ctx.Header("Retry-After", "1")
+				h.abortWithError(ctx, config.ErrorConflict, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+			default:
+				for name, value := range stored.Header {
+					ctx.Header(name, value)
+				}
+				ctx.Header(replayedHeader, "true")
+				ctx.Status(stored.Status)
+				ctx.Writer.Write(stored.Body) //nolint:errcheck
+				ctx.Abort()
+			}
+			return
+		}
+
+		// The outcome is stored even when the client went away, since that
+		// is when it retries.
+		storeCtx := context.WithoutCancel(ctx)
+
+		writer := &recordingWriter{ResponseWriter: ctx.Writer, limit: h.Config.Idempotency.MaxResponse}
+		ctx.Writer = writer
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.UseCase.IdempotencyRepo.Release(storeCtx, key, hash); err != nil {
+				h.Logger.Error(fmt.Errorf("handler - Idempotency - Release: %w", err))
+			}
+		}()
+
+		ctx.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || writer.overflow {
+			return
+		}
+
+		response := entity.IdempotentResponse{
+			Key:         key,
+			RequestHash: hash,
+			Status:      status,
+			Header:      map[string]string{},
+			Body:        writer.body.Bytes(),
+		}
+		for _, name := range idempotentHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				response.Header[name] = value
+			}
+		}
+
+		if err = h.UseCase.IdempotencyRepo.Complete(storeCtx, response, h.Config.Idempotency.TTL); err != nil {
+			h.Logger.Error(fmt.Errorf("handler - Idempotency - Complete: %w", err))
+			return
+		}
+		completed = true
+	}
+}
+
+// readBody reads the body of req, at most limit bytes when limit is
+// positive, and puts it back for the handler.
+func readBody(req *http.Request, limit int) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	reader := io.Reader(req.Body)
+	if limit > 0 {
+		reader = io.LimitReader(req.Body, int64(limit)+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("request body could not be read")
+	}
+	if limit > 0 && len(body) > limit {
+		return nil, fmt.Errorf("request body with Idempotency-Key may have at most %d bytes", limit)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// requestHash identifies a request by its method, URL, content type and body.
+func requestHash(req *http.Request, body []byte) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s %s\n%s\n", req.Method, req.URL.RequestURI(), req.Header.Get("Content-Type"))
+	sum.Write(body)
+
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body, up to limit bytes.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *recordingWriter) record(p []byte) {
+	if w.overflow {
+		return
+	}
+	if w.limit > 0 && w.body.Len()+len(p) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(p)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.record(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// GetJob godoc
+// @Router /jobs/{id} [get]
+// @Summary Get a job by ID
+// @Description Get the state, progress and, once finished, the result or error of a background job
+// @Security BearerAuth
+// @Tags job
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 200 {object} entity.Job
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetJob(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	job, err := h.UseCase.JobRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting job") {
+		return
+	}
+
+	ctx.JSON(200, job)
+}
+
+// GetJobs godoc
+// @Router /jobs [get]
+// @Summary Get a list of jobs
+// @Description Get a list of the background jobs of the tenant
+// @Security BearerAuth
+// @Tags job
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Param filter[column][op] query string false "filter, e.g. filter[state]=running; op defaults to eq"
+// @Param sort query string false "comma separated columns, - for descending, e.g. -created_at"
+// @Success 200 {object} entity.JobList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetJobs(ctx *gin.Context) {
+	req, err := listQuery(ctx, h.Config.HTTP.MaxLimit, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, err.Error(), 400)
+		return
+	}
+
+	jobs, err := h.UseCase.JobRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting jobs") {
+		return
+	}
+
+	ctx.JSON(200, jobs)
+}
+
+// CancelJob godoc
+// @Router /jobs/{id}/cancel [post]
+// @Summary Cancel a job
+// @Description Cancel a queued job, or ask the worker running it to stop. A running job is cancelled within seconds; it keeps the state running until then.
+// @Security BearerAuth
+// @Tags job
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 200 {object} entity.Job
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) CancelJob(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	job, err := h.UseCase.JobRepo.Cancel(ctx, req)
+	if h.HandleDbError(ctx, err, "Error cancelling job") {
+		return
+	}
+
+	ctx.JSON(200, job)
+}
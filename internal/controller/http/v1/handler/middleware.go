@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/audit"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	apiKeyHeader   = "X-API-Key"
+	tenantHeader   = "X-Tenant-ID"
+	adminKeyHeader = "X-Admin-Key"
+	actorHeader    = "X-Actor"
+
+	// tenantCacheTTL bounds how long quota changes take to reach a running instance.
+	tenantCacheTTL = time.Minute
+
+	// maxActorLength keeps "tenant:<name>/<actor>" within the 256 characters
+	// of the actor columns, as tenant names have at most 128.
+	maxActorLength = 120
+)
+
+// actorPattern matches the X-Actor values recorded in the audit trail.
+var actorPattern = regexp.MustCompile(`^[A-Za-z0-9 ._@:+/-]{1,` + strconv.Itoa(maxActorLength) + `}$`)
+
+// ResolveTenant determines the tenant a request acts for and stores it in the
+// request context: from the X-API-Key header, from X-Tenant-ID when the
+// deployment trusts it, or the default tenant when keys are not required.
+// It also applies the tenant's per-minute request limit and records the
+// actor of the request for the audit trail.
+func (h *Handler) ResolveTenant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var (
+			cacheKey string
+			load     func() (entity.Tenant, error)
+		)
+
+		apiKey := ctx.GetHeader(apiKeyHeader)
+		headerID := ctx.GetHeader(tenantHeader)
+
+		switch {
+		case apiKey != "":
+			cacheKey = "key:" + apiKey
+			load = func() (entity.Tenant, error) {
+				return h.UseCase.TenantRepo.GetByApiKey(ctx, apiKey)
+			}
+		case headerID != "" && h.Config.Tenant.AllowHeader:
+			if _, err := uuid.Parse(headerID); err != nil {
+				h.abortWithError(ctx, config.ErrorUnauthorized, "Unknown tenant", http.StatusUnauthorized)
+				return
+			}
+			cacheKey = "id:" + headerID
+			load = func() (entity.Tenant, error) {
+				return h.UseCase.TenantRepo.GetSingle(ctx, entity.Id{ID: headerID})
+			}
+		case !h.Config.Tenant.RequireApiKey:
+			cacheKey = "id:" + h.Config.Tenant.DefaultID
+			load = func() (entity.Tenant, error) {
+				return h.UseCase.TenantRepo.GetSingle(ctx, entity.Id{ID: h.Config.Tenant.DefaultID})
+			}
+		default:
+			h.abortWithError(ctx, config.ErrorUnauthorized, "API key is required", http.StatusUnauthorized)
+			return
+		}
+
+		t, ok := h.tenants.get(cacheKey, time.Now())
+		if !ok {
+			var err error
+
+			t, err = load()
+			if err == pgx.ErrNoRows {
+				h.abortWithError(ctx, config.ErrorUnauthorized, "Unknown API key or tenant", http.StatusUnauthorized)
+				return
+			}
+			if h.HandleDbError(ctx, err, "Error resolving tenant") {
+				ctx.Abort()
+				return
+			}
+
+			h.tenants.set(cacheKey, t, time.Now().Add(tenantCacheTTL))
+		}
+
+		if allowed, retryAfter := h.limiter.allow(t.ID, t.RequestsPerMinute, time.Now()); !allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+1)))
+			h.abortWithError(ctx, config.ErrorTooManyRequest, "Request rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		// The actor recorded in the movie history is the tenant, qualified
+		// by whoever the calling service names, so a caller can only speak
+		// for users of its own tenant.
+		actor := "tenant:" + t.Name
+		if name := ctx.GetHeader(actorHeader); name != "" {
+			if !actorPattern.MatchString(name) {
+				h.abortWithError(ctx, config.ErrorBadRequest, fmt.Sprintf("%s may have at most %d letters, digits, spaces and . _ @ : + / - characters", actorHeader, maxActorLength), http.StatusBadRequest)
+				return
+			}
+			actor += "/" + name
+		}
+
+		reqCtx := tenant.WithID(ctx.Request.Context(), t.ID)
+		reqCtx = audit.WithActor(reqCtx, actor)
+
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
+
+// RequireAdmin guards tenant administration with the configured admin key.
+// Administration is disabled when no key is configured.
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		adminKey := h.Config.Tenant.AdminKey
+		if adminKey == "" {
+			h.abortWithError(ctx, config.ErrorForbidden, "Tenant administration is disabled", http.StatusForbidden)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(ctx.GetHeader(adminKeyHeader)), []byte(adminKey)) != 1 {
+			h.abortWithError(ctx, config.ErrorUnauthorized, "Invalid admin key", http.StatusUnauthorized)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func (h *Handler) abortWithError(ctx *gin.Context, code string, message string, statusCode int) {
+	h.ReturnError(ctx, code, message, statusCode)
+	ctx.Abort()
+}
+
+type tenantCacheEntry struct {
+	tenant  entity.Tenant
+	expires time.Time
+}
+
+// tenantCache keeps resolved tenants for a short while so that every request
+// does not cost an extra query.
+type tenantCache struct {
+	mu      sync.Mutex
+	entries map[string]tenantCacheEntry
+}
+
+func newTenantCache() *tenantCache {
+	return &tenantCache{entries: map[string]tenantCacheEntry{}}
+}
+
+func (c *tenantCache) get(key string, now time.Time) (entity.Tenant, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || now.After(e.expires) {
+		delete(c.entries, key)
+		return entity.Tenant{}, false
+	}
+
+	return e.tenant, true
+}
+
+func (c *tenantCache) set(key string, t entity.Tenant, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = tenantCacheEntry{tenant: t, expires: expires}
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimiter counts requests per tenant in fixed one-minute windows. Limits
+// are per instance; running several replicas multiplies the effective limit.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: map[string]*rateWindow{}}
+}
+
+// allow records a request for id and reports whether it fits in limit; when
+// it does not, the time until the current window ends is returned as well.
+func (l *rateLimiter) allow(id string, limit int, now time.Time) (bool, time.Duration) {
+	if limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[id]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &rateWindow{start: now.Truncate(time.Minute)}
+		l.windows[id] = w
+	}
+
+	if w.count >= limit {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+
+	w.count++
+	return true, 0
+}
@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/abdulazizax/ai-embedding/config"
 	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/records"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,11 +25,15 @@ import (
 // @Accept  json
 // @Produce  json
 // @Param movie body entity.Movie true "Movie object"
+// @Param force query bool false "Create the movie even when it looks like a stored one"
+// @Param Idempotency-Key header string false "Key under which the response is replayed to retries"
 // @Success 201 {object} entity.Movie
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.MovieDuplicateError
 func (h *Handler) CreateMovie(ctx *gin.Context) {
 	var (
-		body entity.Movie
+		body       entity.Movie
+		duplicates []entity.MovieDuplicate
 	)
 
 	err := ctx.ShouldBindJSON(&body)
@@ -30,11 +42,33 @@ func (h *Handler) CreateMovie(ctx *gin.Context) {
 		return
 	}
 
+	mode := h.Config.Dedup.Mode
+	if mode == entity.DedupWarn || mode == entity.DedupReject {
+		duplicates, err = h.UseCase.MovieRepo.FindDuplicates(ctx, body)
+		if h.HandleDbError(ctx, err, "Error checking for duplicate movies") {
+			return
+		}
+
+		force, _ := strconv.ParseBool(ctx.Query("force"))
+		if mode == entity.DedupReject && len(duplicates) > 0 && !force {
+			ctx.JSON(409, entity.MovieDuplicateError{
+				Message:    "Movie looks like a stored one, repeat with force=true to create it anyway",
+				Code:       config.ErrorConflict,
+				Duplicates: duplicates,
+			})
+			return
+		}
+	}
+
 	movie, err := h.UseCase.MovieRepo.Create(ctx, body)
 	if h.HandleDbError(ctx, err, "Error creating movie") {
 		return
 	}
+	if len(duplicates) > 0 {
+		movie.Duplicates = duplicates
+	}
 
+	ctx.Header("ETag", movieETag(movie.Version))
 	ctx.JSON(201, movie)
 }
 
@@ -47,7 +81,9 @@ func (h *Handler) CreateMovie(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Movie ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} entity.Movie
+// @Success 304 "Not modified"
 // @Failure 400 {object} entity.ErrorResponse
 func (h *Handler) GetMovie(ctx *gin.Context) {
 	var (
@@ -61,6 +97,46 @@ func (h *Handler) GetMovie(ctx *gin.Context) {
 		return
 	}
 
+	if notModified(ctx, movieETag(movie.Version)) {
+		return
+	}
+
+	ctx.JSON(200, movie)
+}
+
+// GetMovieBySlug godoc
+// @Router /movie/by-slug/{slug} [get]
+// @Summary Get a movie by slug
+// @Description Get a movie by slug; an earlier slug of a renamed movie is redirected to the current one
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Movie slug"
+// @Success 200 {object} entity.Movie
+// @Success 301 "Moved to the current slug"
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetMovieBySlug(ctx *gin.Context) {
+	var (
+		req entity.MovieSingleRequest
+	)
+
+	req.Slug = ctx.Param("slug")
+
+	movie, err := h.UseCase.MovieRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting movie") {
+		return
+	}
+
+	if movie.Slug != req.Slug {
+		ctx.Redirect(http.StatusMovedPermanently, slugLocation(ctx, req.Slug, movie.Slug))
+		return
+	}
+
+	if notModified(ctx, movieETag(movie.Version)) {
+		return
+	}
+
 	ctx.JSON(200, movie)
 }
 
@@ -74,27 +150,21 @@ func (h *Handler) GetMovie(ctx *gin.Context) {
 // @Produce  json
 // @Param page query number false "page"
 // @Param limit query number false "limit"
+// @Param filter[column][op] query string false "filter, e.g. filter[name_en][search]=matrix; op defaults to eq"
+// @Param sort query string false "comma separated columns, - for descending, e.g. -created_at,name_en"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page; takes precedence over page"
+// @Param facets query string false "comma separated facets to count: genre, year, language"
 // @Success 200 {object} entity.MovieList
 // @Failure 400 {object} entity.ErrorResponse
 func (h *Handler) GetMovies(ctx *gin.Context) {
-	var (
-		req entity.GetListFilter
-	)
-
-	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	if err != nil || limit < 1 {
-		limit = 10
-	}
-
-	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+	req, err := listQuery(ctx, h.Config.HTTP.MaxLimit, entity.OrderBy{
 		Column: "created_at",
 		Order:  "desc",
 	})
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, err.Error(), 400)
+		return
+	}
 
 	movies, err := h.UseCase.MovieRepo.GetList(ctx, req)
 	if h.HandleDbError(ctx, err, "Error getting movie") {
@@ -113,8 +183,10 @@ func (h *Handler) GetMovies(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param movie body entity.Movie true "Movie object"
+// @Param If-Match header string false "ETag the update is based on"
 // @Success 200 {object} entity.Movie
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 412 {object} entity.ErrorResponse
 func (h *Handler) UpdateMovie(ctx *gin.Context) {
 	var (
 		body entity.Movie
@@ -126,25 +198,171 @@ func (h *Handler) UpdateMovie(ctx *gin.Context) {
 		return
 	}
 
+	version, err := ifMatchVersion(ctx)
+	if h.HandleDbError(ctx, err, "Error reading If-Match") {
+		return
+	}
+	if version != 0 {
+		body.Version = version
+	}
+
 	movie, err := h.UseCase.MovieRepo.Update(ctx, body)
 	if h.HandleDbError(ctx, err, "Error updating movie") {
 		return
 	}
 
+	ctx.Header("ETag", movieETag(movie.Version))
+	ctx.JSON(200, movie)
+}
+
+// UpsertExternalMovie godoc
+// @Router /movie/external/{source}/{id} [put]
+// @Summary Create or replace a movie by external id
+// @Description Create the movie with the given source and external id, or replace it when it exists, restoring it from the trash. The movie is only embedded again when its embedded text changed; a request that changes nothing leaves the movie and its version as they are.
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param source path string true "Catalog the external id belongs to"
+// @Param id path string true "Id of the movie in that catalog"
+// @Param movie body entity.Movie true "Movie object"
+// @Param If-Match header string false "ETag the update is based on"
+// @Success 200 {object} entity.Movie
+// @Success 201 {object} entity.Movie
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 412 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
+func (h *Handler) UpsertExternalMovie(ctx *gin.Context) {
+	var (
+		body entity.Movie
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if h.HandleDbError(ctx, err, "Error reading If-Match") {
+		return
+	}
+	if version != 0 {
+		body.Version = version
+	}
+
+	body.ExternalSource, body.ExternalID = ctx.Param("source"), ctx.Param("id")
+
+	movie, created, err := h.UseCase.MovieRepo.UpsertExternal(ctx, body)
+	if h.HandleDbError(ctx, err, "Error upserting movie") {
+		return
+	}
+
+	ctx.Header("ETag", movieETag(movie.Version))
+	if created {
+		ctx.Header("Location", "/v1/movie/"+movie.ID)
+		ctx.JSON(201, movie)
+		return
+	}
+	ctx.JSON(200, movie)
+}
+
+// PatchMovie godoc
+// @Router /movie/{id} [patch]
+// @Summary Partially update a movie
+// @Description Apply a JSON Merge Patch (application/merge-patch+json or application/json) or a JSON Patch (application/json-patch+json) to a movie
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Movie ID"
+// @Param patch body object true "Merge patch or JSON patch document"
+// @Param If-Match header string false "ETag the patch is based on"
+// @Success 200 {object} entity.Movie
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 412 {object} entity.ErrorResponse
+// @Failure 415 {object} entity.ErrorResponse
+func (h *Handler) PatchMovie(ctx *gin.Context) {
+	var (
+		req entity.MoviePatchRequest
+	)
+
+	switch ctx.ContentType() {
+	case "application/json-patch+json":
+		req.Format = entity.PatchJSON
+	case "application/merge-patch+json", "application/json", "":
+		req.Format = entity.PatchMerge
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unsupported patch media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := ctx.GetRawData()
+	if err != nil || len(patch) == 0 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	req.Version, err = ifMatchVersion(ctx)
+	if h.HandleDbError(ctx, err, "Error reading If-Match") {
+		return
+	}
+
+	req.ID = ctx.Param("id")
+	req.Patch = patch
+
+	movie, err := h.UseCase.MovieRepo.Patch(ctx, req)
+	if h.HandleDbError(ctx, err, "Error patching movie") {
+		return
+	}
+
+	ctx.Header("ETag", movieETag(movie.Version))
 	ctx.JSON(200, movie)
 }
 
+// BulkUpdateMovies godoc
+// @Router /movie/bulk-update [post]
+// @Summary Update columns of many movies
+// @Description Set allowlisted columns on every movie matching a required filter whose values may not be empty; dry_run only counts the matches. Filters matching more movies than the configured maximum are rejected. Movies whose embedded text changes are re-embedded.
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param request body entity.UpdateFieldRequest true "Filter and column values"
+// @Success 200 {object} entity.RowsEffected
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) BulkUpdateMovies(ctx *gin.Context) {
+	var (
+		body entity.UpdateFieldRequest
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	resp, err := h.UseCase.MovieRepo.UpdateField(ctx, body)
+	if h.HandleDbError(ctx, err, "Error updating movies") {
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
 // DeleteMovie godoc
 // @Router /movie/{id} [delete]
 // @Summary Delete a movie
-// @Description Delete a movie
+// @Description Move a movie to the trash
 // @Security BearerAuth
 // @Tags movie
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Movie ID"
+// @Param If-Match header string false "ETag the deletion is based on"
 // @Success 200 {object} entity.SuccessResponse
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 412 {object} entity.ErrorResponse
 func (h *Handler) DeleteMovie(ctx *gin.Context) {
 	var (
 		req entity.Id
@@ -152,7 +370,287 @@ func (h *Handler) DeleteMovie(ctx *gin.Context) {
 
 	req.ID = ctx.Param("id")
 
-	err := h.UseCase.MovieRepo.Delete(ctx, req)
+	version, err := ifMatchVersion(ctx)
+	if h.HandleDbError(ctx, err, "Error reading If-Match") {
+		return
+	}
+	req.Version = version
+
+	err = h.UseCase.MovieRepo.Delete(ctx, req)
+	if h.HandleDbError(ctx, err, "Error deleting movie") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Movie deleted successfully",
+	})
+}
+
+// GetMovieTrash godoc
+// @Router /movie/trash [get]
+// @Summary Get a list of deleted movies
+// @Description Get the movies in the trash; they can be restored until they are purged
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Param filter[column][op] query string false "filter, e.g. filter[release_year][gte]=2000; op defaults to eq"
+// @Param sort query string false "comma separated columns, - for descending"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page; takes precedence over page"
+// @Param facets query string false "comma separated facets to count: genre, year, language"
+// @Success 200 {object} entity.MovieList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMovieTrash(ctx *gin.Context) {
+	req, err := listQuery(ctx, h.Config.HTTP.MaxLimit, entity.OrderBy{
+		Column: "deleted_at",
+		Order:  "desc",
+	})
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, err.Error(), 400)
+		return
+	}
+
+	movies, err := h.UseCase.MovieRepo.GetTrash(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting deleted movies") {
+		return
+	}
+
+	ctx.JSON(200, movies)
+}
+
+// RestoreMovie godoc
+// @Router /movie/{id}/restore [post]
+// @Summary Restore a deleted movie
+// @Description Move a movie out of the trash
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} entity.Movie
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) RestoreMovie(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	movie, err := h.UseCase.MovieRepo.Restore(ctx, req)
+	if h.HandleDbError(ctx, err, "Error restoring movie") {
+		return
+	}
+
+	ctx.Header("ETag", movieETag(movie.Version))
+	ctx.JSON(200, movie)
+}
+
+// GetMovieHistory godoc
+// @Router /movie/{id}/history [get]
+// @Summary Get the revision history of a movie
+// @Description Get who changed a movie, when, and what changed, newest first
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Movie ID"
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.MovieRevisionList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMovieHistory(ctx *gin.Context) {
+	var (
+		req entity.MovieHistoryRequest
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.MovieID = ctx.Param("id")
+	req.Page = page
+	req.Limit = limit
+
+	history, err := h.UseCase.MovieRepo.GetHistory(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting movie history") {
+		return
+	}
+
+	ctx.JSON(200, history)
+}
+
+// RevertMovie godoc
+// @Router /movie/{id}/revert [post]
+// @Summary Revert a movie to a revision
+// @Description Restore the content a movie had after the given revision; the revert is recorded as a new revision
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Movie ID"
+// @Param revert body entity.MovieRevertRequest true "Revision to revert to"
+// @Success 200 {object} entity.Movie
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) RevertMovie(ctx *gin.Context) {
+	var (
+		body entity.MovieRevertRequest
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.Revision < 1 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	body.MovieID = ctx.Param("id")
+
+	movie, err := h.UseCase.MovieRepo.Revert(ctx, body)
+	if h.HandleDbError(ctx, err, "Error reverting movie") {
+		return
+	}
+
+	ctx.Header("ETag", movieETag(movie.Version))
+	ctx.JSON(200, movie)
+}
+
+// GetMovieDuplicates godoc
+// @Router /movie/duplicates [get]
+// @Summary List likely duplicate movies
+// @Description Group the movies outside the trash into clusters connected by similar names in the same language or by embeddings the embedding workers flagged as similar, strongest cluster first. Only the most similar pairs of each check are clustered; truncated is set when pairs were left out. With rescan the embeddings of all movies are compared again first, which runs as a job.
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param limit query number false "limit"
+// @Param async query boolean false "Queue the report as a job and answer 202 with it"
+// @Param rescan query boolean false "Flag the vector look-alikes of every movie again first, e.g. after changing the threshold; implies async"
+// @Success 200 {object} entity.MovieDuplicateReport
+// @Success 202 {object} entity.Job
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMovieDuplicates(ctx *gin.Context) {
+	var (
+		req entity.MovieDuplicateReportRequest
+	)
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if maxLimit := h.Config.HTTP.MaxLimit; maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	req.Limit = limit
+
+	req.Rescan, _ = strconv.ParseBool(ctx.Query("rescan"))
+	if async, _ := strconv.ParseBool(ctx.Query("async")); async || req.Rescan {
+		payload, err := json.Marshal(req)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorInternalServer, err.Error(), 500)
+			return
+		}
+
+		// One report per tenant runs at a time.
+		job, err := h.UseCase.JobRepo.Enqueue(ctx, entity.Job{
+			Kind:      entity.JobMovieDuplicates,
+			Payload:   payload,
+			UniqueKey: entity.JobMovieDuplicates + ":" + tenant.ID(ctx),
+		})
+		if h.HandleDbError(ctx, err, "Error queueing duplicate report") {
+			return
+		}
+
+		ctx.Header("Location", "/v1/jobs/"+job.ID)
+		ctx.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	report, err := h.UseCase.MovieRepo.DuplicateReport(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting duplicate movies") {
+		return
+	}
+
+	ctx.JSON(200, report)
+}
+
+// MergeMovies godoc
+// @Router /movie/{id}/merge [post]
+// @Summary Merge duplicates into a movie
+// @Description Fold the given duplicates into the movie and move them to the trash. The movie keeps its content, takes over the empty fields, names, aliases, genres and cast of the duplicates, and their slugs redirect to it.
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Movie ID"
+// @Param merge body entity.MovieMergeRequest true "Movies to merge into it"
+// @Param If-Match header string false "ETag the merge is based on"
+// @Success 200 {object} entity.Movie
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 412 {object} entity.ErrorResponse
+func (h *Handler) MergeMovies(ctx *gin.Context) {
+	var (
+		body entity.MovieMergeRequest
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	body.Version, err = ifMatchVersion(ctx)
+	if h.HandleDbError(ctx, err, "Error reading If-Match") {
+		return
+	}
+
+	body.ID = ctx.Param("id")
+
+	movie, err := h.UseCase.MovieRepo.Merge(ctx, body)
+	if h.HandleDbError(ctx, err, "Error merging movies") {
+		return
+	}
+
+	ctx.Header("ETag", movieETag(movie.Version))
+	ctx.JSON(200, movie)
+}
+
+// DeleteMovieBySlug godoc
+// @Router /movie/by-slug/{slug} [delete]
+// @Summary Delete a movie by slug
+// @Description Delete a movie by its current slug; an earlier slug is redirected to the current one
+// @Security BearerAuth
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Movie slug"
+// @Success 200 {object} entity.SuccessResponse
+// @Success 308 "Moved to the current slug"
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) DeleteMovieBySlug(ctx *gin.Context) {
+	var (
+		req entity.MovieSingleRequest
+	)
+
+	req.Slug = ctx.Param("slug")
+
+	movie, err := h.UseCase.MovieRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting movie") {
+		return
+	}
+
+	// A stale slug must not silently delete whatever movie it now points to;
+	// the client has to repeat the request against the current slug.
+	if movie.Slug != req.Slug {
+		ctx.Redirect(http.StatusPermanentRedirect, slugLocation(ctx, req.Slug, movie.Slug))
+		return
+	}
+
+	err = h.UseCase.MovieRepo.Delete(ctx, entity.Id{ID: movie.ID})
 	if h.HandleDbError(ctx, err, "Error deleting movie") {
 		return
 	}
@@ -162,6 +660,161 @@ func (h *Handler) DeleteMovie(ctx *gin.Context) {
 	})
 }
 
+// slugLocation returns the URL of the current request with its trailing
+// slug replaced.
+func slugLocation(ctx *gin.Context, from, to string) string {
+	location := strings.TrimSuffix(ctx.Request.URL.Path, from) + url.PathEscape(to)
+	if ctx.Request.URL.RawQuery != "" {
+		location += "?" + ctx.Request.URL.RawQuery
+	}
+
+	return location
+}
+
+// ImportMovies godoc
+// @Router /movie/import [post]
+// @Summary Import movies
+// @Description Create or update movies from a streamed CSV (with a header row), JSON Lines, JSON array or Arrow IPC stream upload, matched by source and external_id. Columns are read by field name unless mapped with map[field]=column; aliases and genres take "|" separated values in CSV. Movies in the trash are restored. An embedding column with the embedding_model it was made by, as /movie/export writes them, is stored instead of embedding the movie again when the model is the configured one. The report lists the outcome of every line.
+// @Security BearerAuth
+// @Tags movie
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Accept  json
+// @Accept  application/vnd.apache.arrow.stream
+// @Produce  json
+// @Param source query string true "Catalog the external ids belong to"
+// @Param format query string false "csv, ndjson, json or arrow; defaults to the Content-Type"
+// @Param map[field] query string false "upload column to read a field from, e.g. map[name_en]=title"
+// @Param async query boolean false "Queue the import as a job and answer 202 with it instead of waiting for the report"
+// @Param body body string true "Upload"
+// @Success 200 {object} entity.MovieImportReport
+// @Success 202 {object} entity.Job
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 413 {object} entity.ErrorResponse
+// @Failure 415 {object} entity.ErrorResponse
+func (h *Handler) ImportMovies(ctx *gin.Context) {
+	var (
+		req entity.MovieImportRequest
+		err error
+	)
+
+	req.Format = records.Format(ctx.DefaultQuery("format", ctx.ContentType()))
+	if req.Format == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unsupported import format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	req.Mapping, err = mappingQuery(ctx)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, err.Error(), 400)
+		return
+	}
+
+	req.Source = ctx.Query("source")
+
+	if async, _ := strconv.ParseBool(ctx.Query("async")); async {
+		h.enqueueImport(ctx, req)
+		return
+	}
+
+	req.Body = ctx.Request.Body
+
+	report, err := h.UseCase.MovieRepo.Import(ctx, req)
+	if h.HandleDbError(ctx, err, "Error importing movies") {
+		return
+	}
+
+	ctx.JSON(200, report)
+}
+
+// enqueueImport stores the upload with an import job and answers 202 with
+// the job; GET /jobs/{id} reports its progress and, once done, the report.
+func (h *Handler) enqueueImport(ctx *gin.Context, req entity.MovieImportRequest) {
+	var (
+		body     io.Reader = ctx.Request.Body
+		maxInput           = h.Config.Jobs.MaxInput
+	)
+	if maxInput > 0 {
+		body = io.LimitReader(body, int64(maxInput)+1)
+	}
+
+	input, err := io.ReadAll(body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Could not read upload", 400)
+		return
+	}
+	if maxInput > 0 && len(input) > maxInput {
+		h.ReturnError(ctx, config.ErrorBadRequest, fmt.Sprintf("Uploads imported asynchronously may be at most %d bytes", maxInput), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, err.Error(), 500)
+		return
+	}
+
+	job, err := h.UseCase.JobRepo.Enqueue(ctx, entity.Job{
+		Kind:    entity.JobMovieImport,
+		Payload: payload,
+		Input:   input,
+	})
+	if h.HandleDbError(ctx, err, "Error queueing movie import") {
+		return
+	}
+
+	ctx.Header("Location", "/v1/jobs/"+job.ID)
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// exportContentTypes maps the export formats to their Content-Type.
+var exportContentTypes = map[string]string{
+	records.FormatCSV:    "text/csv; charset=utf-8",
+	records.FormatNDJSON: "application/x-ndjson",
+	records.FormatArrow:  "application/vnd.apache.arrow.stream",
+}
+
+// ExportMovies godoc
+// @Router /movie/export [get]
+// @Summary Export movies
+// @Description Stream every movie outside the trash, ordered by id, with genre slugs and the embedding together with the embedding_model and embedding_template_hash it was made with. Embeddings that are not ready are null. Uploading the export to /movie/import with map[external_id]=id restores the catalog without embedding it again. CSV joins lists with "|" and writes embeddings as [x,y,...]; arrow is an Arrow IPC stream.
+// @Security BearerAuth
+// @Tags movie
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Produce  application/vnd.apache.arrow.stream
+// @Param format query string false "csv, ndjson or arrow; defaults to ndjson"
+// @Success 200 {string} string
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ExportMovies(ctx *gin.Context) {
+	format := records.Format(ctx.DefaultQuery("format", records.FormatNDJSON))
+
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unsupported export format", 400)
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="movies.%s"`, format))
+
+	count, err := h.UseCase.MovieRepo.Export(ctx, entity.MovieExportRequest{Format: format, Writer: ctx.Writer})
+	if err != nil && ctx.Writer.Written() {
+		// The status is sent already; the client sees a truncated export.
+		h.Logger.Error(err, fmt.Sprintf("Error exporting movies after %d movies", count))
+		return
+	}
+	if err != nil {
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+	}
+	if h.HandleDbError(ctx, err, "Error exporting movies") {
+		return
+	}
+
+	ctx.Status(200)
+}
+
 // SearchMovie godoc
 // @Router /movie/search [get]
 // @Summary Get movies by search query
@@ -170,20 +823,24 @@ func (h *Handler) DeleteMovie(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param search query string false "Search query"
-// @Success 200 {array} entity.Movie
+// @Param limit query number false "limit"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous result page"
+// @Param facets query string false "comma separated facets to count over the movies matching the query: genre, year, language"
+// @Param explain query boolean false "Return per-hit scoring details and the query plan"
+// @Success 200 {object} entity.MovieList
 // @Failure 400 {object} entity.ErrorResponse
 func (h *Handler) SearchMovie(ctx *gin.Context) {
 	var (
-		req entity.MovieSingleRequest
+		req entity.MovieSearchRequest
 	)
 
-	search := ctx.DefaultQuery("search", "")
+	req.Query = ctx.DefaultQuery("search", "")
 
-	if search != "" {
-		req.NameUz = search
-		req.NameRu = search
-		req.NameEn = search
-	}
+	req.Limit = limitQuery(ctx, h.Config.HTTP.MaxLimit)
+	req.Cursor = ctx.Query("cursor")
+	req.Facets = facetsQuery(ctx)
+
+	req.Explain, _ = strconv.ParseBool(ctx.DefaultQuery("explain", "false"))
 
 	resp, err := h.UseCase.MovieRepo.Search(ctx, req)
 	if h.HandleDbError(ctx, err, "Error searching movie") {
@@ -192,3 +849,42 @@ func (h *Handler) SearchMovie(ctx *gin.Context) {
 
 	ctx.JSON(200, resp)
 }
+
+// SuggestMovie godoc
+// @Router /movie/suggest [get]
+// @Summary Suggest movie titles
+// @Description Typeahead suggestions over movie titles, served from trigram indexes without calling the embedder
+// @Tags movie
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Typed prefix"
+// @Param lang query string false "Title language (uz, en, ru)"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.MovieSuggestList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) SuggestMovie(ctx *gin.Context) {
+	var (
+		req entity.MovieSuggestRequest
+	)
+
+	req.Query = strings.TrimSpace(ctx.Query("q"))
+	if req.Query == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Query parameter q is required", 400)
+		return
+	}
+
+	req.Lang = ctx.Query("lang")
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+	if limit > 20 {
+		limit = 20
+	}
+	req.Limit = limit
+
+	resp, err := h.UseCase.MovieRepo.Suggest(ctx, req)
+	if h.HandleDbError(ctx, err, "Error suggesting movie") {
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePerson godoc
+// @Router /person [post]
+// @Summary Create a new person
+// @Description Create a new person
+// @Security BearerAuth
+// @Tags person
+// @Accept  json
+// @Produce  json
+// @Param person body entity.Person true "Person object"
+// @Success 201 {object} entity.Person
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreatePerson(ctx *gin.Context) {
+	var (
+		body entity.Person
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.FullName) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	person, err := h.UseCase.PersonRepo.Create(ctx, body)
+	if h.HandleDbError(ctx, err, "Error creating person") {
+		return
+	}
+
+	ctx.JSON(201, person)
+}
+
+// GetPerson godoc
+// @Router /person/{id} [get]
+// @Summary Get a person by ID
+// @Description Get a person by ID
+// @Security BearerAuth
+// @Tags person
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Person ID"
+// @Success 200 {object} entity.Person
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetPerson(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	person, err := h.UseCase.PersonRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting person") {
+		return
+	}
+
+	ctx.JSON(200, person)
+}
+
+// GetPeople godoc
+// @Router /person/list [get]
+// @Summary Get a list of people
+// @Description Get a list of people
+// @Security BearerAuth
+// @Tags person
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.PersonList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetPeople(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "full_name",
+		Order:  "asc",
+	})
+
+	people, err := h.UseCase.PersonRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting people") {
+		return
+	}
+
+	ctx.JSON(200, people)
+}
+
+// UpdatePerson godoc
+// @Router /person [put]
+// @Summary Update a person
+// @Description Update a person
+// @Security BearerAuth
+// @Tags person
+// @Accept  json
+// @Produce  json
+// @Param person body entity.Person true "Person object"
+// @Success 200 {object} entity.Person
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdatePerson(ctx *gin.Context) {
+	var (
+		body entity.Person
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.ID == "" || strings.TrimSpace(body.FullName) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	person, err := h.UseCase.PersonRepo.Update(ctx, body)
+	if h.HandleDbError(ctx, err, "Error updating person") {
+		return
+	}
+
+	ctx.JSON(200, person)
+}
+
+// DeletePerson godoc
+// @Router /person/{id} [delete]
+// @Summary Delete a person
+// @Description Delete a person and remove it from the movies it is linked to
+// @Security BearerAuth
+// @Tags person
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Person ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeletePerson(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	err := h.UseCase.PersonRepo.Delete(ctx, req)
+	if h.HandleDbError(ctx, err, "Error deleting person") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Person deleted successfully",
+	})
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// listQuery reads page, limit, cursor, facets, filters and sorting of a list
+// endpoint from the query string:
+//
+//	?page=2&limit=20&filter[name_en][search]=matrix&filter[release_year][gte]=1999&sort=-created_at,name_en
+//
+// filter[col]=v is shorthand for filter[col][eq]=v and a repeated parameter
+// adds one filter per value. Sort columns are comma separated, a leading "-"
+// means descending. defaultOrder is used when sort is absent. Columns and
+// operators are validated by the repository.
+func listQuery(ctx *gin.Context, maxLimit int, defaultOrder ...entity.OrderBy) (entity.GetListFilter, error) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	req.Page = page
+	req.Limit = limitQuery(ctx, maxLimit)
+	req.Cursor = ctx.Query("cursor")
+	req.Facets = facetsQuery(ctx)
+
+	query := ctx.Request.URL.Query()
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		column, op, err := parseFilterKey(key)
+		if err != nil {
+			return req, err
+		}
+
+		for _, value := range query[key] {
+			req.Filters = append(req.Filters, entity.Filter{
+				Column: column,
+				Type:   op,
+				Value:  value,
+			})
+		}
+	}
+
+	sortParam, ok := ctx.GetQuery("sort")
+	if !ok {
+		req.OrderBy = append(req.OrderBy, defaultOrder...)
+		return req, nil
+	}
+
+	for _, column := range strings.Split(sortParam, ",") {
+		column = strings.TrimSpace(column)
+		order := "asc"
+		if strings.HasPrefix(column, "-") {
+			column, order = column[1:], "desc"
+		} else {
+			column = strings.TrimPrefix(column, "+")
+		}
+
+		if column == "" {
+			return req, fmt.Errorf("invalid sort %q", sortParam)
+		}
+
+		req.OrderBy = append(req.OrderBy, entity.OrderBy{
+			Column: column,
+			Order:  order,
+		})
+	}
+
+	return req, nil
+}
+
+// limitQuery reads the limit parameter, 10 by default and at most maxLimit
+// when it is positive.
+func limitQuery(ctx *gin.Context, maxLimit int) int {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return limit
+}
+
+// facetsQuery reads the comma separated facets parameter.
+func facetsQuery(ctx *gin.Context) []string {
+	var facets []string
+	for _, facet := range strings.Split(ctx.Query("facets"), ",") {
+		if facet = strings.TrimSpace(facet); facet != "" {
+			facets = append(facets, facet)
+		}
+	}
+
+	return facets
+}
+
+// mappingQuery reads map[field]=column parameters.
+func mappingQuery(ctx *gin.Context) (map[string]string, error) {
+	mapping := map[string]string{}
+	for key, values := range ctx.Request.URL.Query() {
+		if !strings.HasPrefix(key, "map[") {
+			continue
+		}
+
+		field := strings.TrimSuffix(strings.TrimPrefix(key, "map["), "]")
+		if field == "" || strings.ContainsAny(field, "[]") || len(values) != 1 {
+			return nil, fmt.Errorf("invalid mapping parameter %q", key)
+		}
+		mapping[field] = values[0]
+	}
+
+	return mapping, nil
+}
+
+// parseFilterKey splits filter[col][op] into its column and operator.
+func parseFilterKey(key string) (string, string, error) {
+	rest := strings.TrimPrefix(key, "filter")
+
+	var parts []string
+	for rest != "" {
+		end := strings.Index(rest, "]")
+		if rest[0] != '[' || end < 0 {
+			return "", "", fmt.Errorf("invalid filter parameter %q", key)
+		}
+
+		parts = append(parts, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0], "eq", nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], nil
+	}
+
+	return "", "", fmt.Errorf("invalid filter parameter %q", key)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+func testContext(rawQuery string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/v1/movie/list?"+rawQuery, nil)
+
+	return ctx
+}
+
+func TestListQuery(t *testing.T) {
+	defaultOrder := entity.OrderBy{Column: "created_at", Order: "desc"}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    entity.GetListFilter
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  entity.GetListFilter{Page: 1, Limit: 10, OrderBy: []entity.OrderBy{defaultOrder}},
+		},
+		{
+			name:  "page, limit, cursor and facets",
+			query: "page=3&limit=25&cursor=abc.def&facets=genre,%20year,,",
+			want: entity.GetListFilter{
+				Page: 3, Limit: 25, Cursor: "abc.def", Facets: []string{"genre", "year"},
+				OrderBy: []entity.OrderBy{defaultOrder},
+			},
+		},
+		{
+			name:  "invalid page and limit fall back to the defaults",
+			query: "page=-2&limit=abc",
+			want:  entity.GetListFilter{Page: 1, Limit: 10, OrderBy: []entity.OrderBy{defaultOrder}},
+		},
+		{
+			name:  "limit is capped",
+			query: "limit=5000",
+			want:  entity.GetListFilter{Page: 1, Limit: 100, OrderBy: []entity.OrderBy{defaultOrder}},
+		},
+		{
+			name:  "filters are sorted by key and repeat per value",
+			query: "filter[release_year][gte]=1999&filter[name_en][search]=matrix&filter[country]=US&filter[country]=UZ",
+			want: entity.GetListFilter{
+				Page: 1, Limit: 10,
+				Filters: []entity.Filter{
+					{Column: "country", Type: "eq", Value: "US"},
+					{Column: "country", Type: "eq", Value: "UZ"},
+					{Column: "name_en", Type: "search", Value: "matrix"},
+					{Column: "release_year", Type: "gte", Value: "1999"},
+				},
+				OrderBy: []entity.OrderBy{defaultOrder},
+			},
+		},
+		{
+			name:  "sort",
+			query: "sort=-release_year,%2Bname_en,%20id",
+			want: entity.GetListFilter{
+				Page: 1, Limit: 10,
+				OrderBy: []entity.OrderBy{
+					{Column: "release_year", Order: "desc"},
+					{Column: "name_en", Order: "asc"},
+					{Column: "id", Order: "asc"},
+				},
+			},
+		},
+		{
+			name:    "sort with an empty column",
+			query:   "sort=name_en,,id",
+			wantErr: "invalid sort",
+		},
+		{
+			name:    "sort with only a minus",
+			query:   "sort=-",
+			wantErr: "invalid sort",
+		},
+		{
+			name:    "filter without a column",
+			query:   "filter[]=x",
+			wantErr: "invalid filter parameter",
+		},
+		{
+			name:    "filter with an empty operator",
+			query:   "filter[name_en][]=x",
+			wantErr: "invalid filter parameter",
+		},
+		{
+			name:    "filter nested too deep",
+			query:   "filter[name_en][eq][x]=y",
+			wantErr: "invalid filter parameter",
+		},
+		{
+			name:    "filter with an unclosed bracket",
+			query:   "filter[name_en=x",
+			wantErr: "invalid filter parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listQuery(testContext(tt.query), 100, defaultOrder)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("listQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("listQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSynonym godoc
+// @Router /synonym [post]
+// @Summary Create a new synonym
+// @Description Create a search synonym that expands a term into the phrases it stands for
+// @Security BearerAuth
+// @Tags synonym
+// @Accept  json
+// @Produce  json
+// @Param synonym body entity.Synonym true "Synonym object"
+// @Success 201 {object} entity.Synonym
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateSynonym(ctx *gin.Context) {
+	var (
+		body entity.Synonym
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Term) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	synonym, err := h.UseCase.SynonymRepo.Create(ctx, body)
+	if h.HandleDbError(ctx, err, "Error creating synonym") {
+		return
+	}
+
+	ctx.JSON(201, synonym)
+}
+
+// GetSynonym godoc
+// @Router /synonym/{id} [get]
+// @Summary Get a synonym by ID
+// @Description Get a synonym by ID
+// @Security BearerAuth
+// @Tags synonym
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym ID"
+// @Success 200 {object} entity.Synonym
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetSynonym(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	synonym, err := h.UseCase.SynonymRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting synonym") {
+		return
+	}
+
+	ctx.JSON(200, synonym)
+}
+
+// GetSynonyms godoc
+// @Router /synonym/list [get]
+// @Summary Get a list of synonyms
+// @Description Get a list of synonyms
+// @Security BearerAuth
+// @Tags synonym
+// @Accept  json
+// @Produce  json
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.SynonymList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetSynonyms(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "term",
+		Order:  "asc",
+	})
+
+	synonyms, err := h.UseCase.SynonymRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting synonyms") {
+		return
+	}
+
+	ctx.JSON(200, synonyms)
+}
+
+// UpdateSynonym godoc
+// @Router /synonym [put]
+// @Summary Update a synonym
+// @Description Update a synonym
+// @Security BearerAuth
+// @Tags synonym
+// @Accept  json
+// @Produce  json
+// @Param synonym body entity.Synonym true "Synonym object"
+// @Success 200 {object} entity.Synonym
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateSynonym(ctx *gin.Context) {
+	var (
+		body entity.Synonym
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.ID == "" || strings.TrimSpace(body.Term) == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	synonym, err := h.UseCase.SynonymRepo.Update(ctx, body)
+	if h.HandleDbError(ctx, err, "Error updating synonym") {
+		return
+	}
+
+	ctx.JSON(200, synonym)
+}
+
+// DeleteSynonym godoc
+// @Router /synonym/{id} [delete]
+// @Summary Delete a synonym
+// @Description Delete a synonym
+// @Security BearerAuth
+// @Tags synonym
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteSynonym(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	err := h.UseCase.SynonymRepo.Delete(ctx, req)
+	if h.HandleDbError(ctx, err, "Error deleting synonym") {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Synonym deleted successfully",
+	})
+}
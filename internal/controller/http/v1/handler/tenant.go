@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTenant godoc
+// @Router /tenant [post]
+// @Summary Create a new tenant
+// @Description Create a new tenant; the generated API key is returned only once
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param X-Admin-Key header string true "Admin key"
+// @Param tenant body entity.Tenant true "Tenant object"
+// @Success 201 {object} entity.TenantWithKey
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateTenant(ctx *gin.Context) {
+	var (
+		body entity.Tenant
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || !validTenant(body) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	tenant, err := h.UseCase.TenantRepo.Create(ctx, body)
+	if h.HandleDbError(ctx, err, "Error creating tenant") {
+		return
+	}
+
+	ctx.JSON(201, tenant)
+}
+
+// GetTenant godoc
+// @Router /tenant/{id} [get]
+// @Summary Get a tenant by ID
+// @Description Get a tenant by ID
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param X-Admin-Key header string true "Admin key"
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} entity.Tenant
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetTenant(ctx *gin.Context) {
+	var (
+		req entity.Id
+	)
+
+	req.ID = ctx.Param("id")
+
+	tenant, err := h.UseCase.TenantRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting tenant") {
+		return
+	}
+
+	ctx.JSON(200, tenant)
+}
+
+// GetTenants godoc
+// @Router /tenant/list [get]
+// @Summary Get a list of tenants
+// @Description Get a list of tenants
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param X-Admin-Key header string true "Admin key"
+// @Param page query number false "page"
+// @Param limit query number false "limit"
+// @Success 200 {object} entity.TenantList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetTenants(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit := limitQuery(ctx, h.Config.HTTP.MaxLimit)
+
+	req.Page = page
+	req.Limit = limit
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "name",
+		Order:  "asc",
+	})
+
+	tenants, err := h.UseCase.TenantRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting tenants") {
+		return
+	}
+
+	ctx.JSON(200, tenants)
+}
+
+// UpdateTenant godoc
+// @Router /tenant [put]
+// @Summary Update a tenant
+// @Description Update the name and quotas of a tenant
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param X-Admin-Key header string true "Admin key"
+// @Param tenant body entity.Tenant true "Tenant object"
+// @Success 200 {object} entity.Tenant
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateTenant(ctx *gin.Context) {
+	var (
+		body entity.Tenant
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.ID == "" || !validTenant(body) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	tenant, err := h.UseCase.TenantRepo.Update(ctx, body)
+	if h.HandleDbError(ctx, err, "Error updating tenant") {
+		return
+	}
+
+	ctx.JSON(200, tenant)
+}
+
+func validTenant(t entity.Tenant) bool {
+	return strings.TrimSpace(t.Name) != "" && t.MaxMovies >= 0 && t.MaxCollections >= 0 && t.RequestsPerMinute >= 0
+}
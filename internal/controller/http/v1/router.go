@@ -29,6 +29,9 @@ import (
 // @name Authorization
 func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useCase *usecase.UseCase) {
 	// Options
+	// Handlers pass *gin.Context down to the repositories, so it has to expose
+	// the values (such as the tenant) stored in the request context.
+	engine.ContextWithFallback = true
 	engine.Use(gin.Logger())
 	engine.Use(gin.Recovery())
 
@@ -47,13 +50,86 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 	// Routes
 	v1 := engine.Group("/v1")
 
-	movie := v1.Group("/movie")
+	tenant := v1.Group("/tenant", handlerV1.RequireAdmin())
+	{
+		tenant.POST("/", handlerV1.CreateTenant)
+		tenant.GET("/list", handlerV1.GetTenants)
+		tenant.GET("/:id", handlerV1.GetTenant)
+		tenant.PUT("/", handlerV1.UpdateTenant)
+	}
+
+	// Write requests may carry an Idempotency-Key header to be retried safely.
+	scoped := v1.Group("", handlerV1.ResolveTenant(), handlerV1.Idempotency())
+
+	movie := scoped.Group("/movie")
 	{
 		movie.POST("/", handlerV1.CreateMovie)
 		movie.GET("/list", handlerV1.GetMovies)
 		movie.GET("/:id", handlerV1.GetMovie)
+		movie.GET("/by-slug/:slug", handlerV1.GetMovieBySlug)
+		movie.DELETE("/by-slug/:slug", handlerV1.DeleteMovieBySlug)
 		movie.PUT("/", handlerV1.UpdateMovie)
+		movie.PUT("/external/:source/:id", handlerV1.UpsertExternalMovie)
+		movie.PATCH("/:id", handlerV1.PatchMovie)
+		movie.POST("/bulk-update", handlerV1.BulkUpdateMovies)
+		movie.POST("/import", handlerV1.ImportMovies)
+		movie.GET("/export", handlerV1.ExportMovies)
 		movie.DELETE("/:id", handlerV1.DeleteMovie)
+		movie.GET("/trash", handlerV1.GetMovieTrash)
+		movie.POST("/:id/restore", handlerV1.RestoreMovie)
+		movie.GET("/:id/history", handlerV1.GetMovieHistory)
+		movie.POST("/:id/revert", handlerV1.RevertMovie)
+		movie.GET("/duplicates", handlerV1.GetMovieDuplicates)
+		movie.POST("/:id/merge", handlerV1.MergeMovies)
 		movie.GET("/search", handlerV1.SearchMovie)
+		movie.GET("/suggest", handlerV1.SuggestMovie)
+	}
+
+	genre := scoped.Group("/genre")
+	{
+		genre.POST("/", handlerV1.CreateGenre)
+		genre.GET("/list", handlerV1.GetGenres)
+		genre.GET("/:id", handlerV1.GetGenre)
+		genre.PUT("/", handlerV1.UpdateGenre)
+		genre.DELETE("/:id", handlerV1.DeleteGenre)
+	}
+
+	person := scoped.Group("/person")
+	{
+		person.POST("/", handlerV1.CreatePerson)
+		person.GET("/list", handlerV1.GetPeople)
+		person.GET("/:id", handlerV1.GetPerson)
+		person.PUT("/", handlerV1.UpdatePerson)
+		person.DELETE("/:id", handlerV1.DeletePerson)
+	}
+
+	collections := scoped.Group("/collections")
+	{
+		collections.POST("/", handlerV1.CreateCollection)
+		collections.GET("/", handlerV1.GetCollections)
+		collections.GET("/:name", handlerV1.GetCollection)
+		collections.DELETE("/:name", handlerV1.DeleteCollection)
+		collections.POST("/:name/documents", handlerV1.CreateDocument)
+		collections.GET("/:name/documents", handlerV1.GetDocuments)
+		collections.GET("/:name/documents/:id", handlerV1.GetDocument)
+		collections.PUT("/:name/documents/:id", handlerV1.UpdateDocument)
+		collections.DELETE("/:name/documents/:id", handlerV1.DeleteDocument)
+		collections.GET("/:name/search", handlerV1.SearchDocuments)
+	}
+
+	synonym := scoped.Group("/synonym")
+	{
+		synonym.POST("/", handlerV1.CreateSynonym)
+		synonym.GET("/list", handlerV1.GetSynonyms)
+		synonym.GET("/:id", handlerV1.GetSynonym)
+		synonym.PUT("/", handlerV1.UpdateSynonym)
+		synonym.DELETE("/:id", handlerV1.DeleteSynonym)
+	}
+
+	jobs := scoped.Group("/jobs")
+	{
+		jobs.GET("/", handlerV1.GetJobs)
+		jobs.GET("/:id", handlerV1.GetJob)
+		jobs.POST("/:id/cancel", handlerV1.CancelJob)
 	}
 }
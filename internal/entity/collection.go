@@ -0,0 +1,46 @@
+package entity
+
+type (
+	// CollectionField describes one typed field of the documents in a collection.
+	CollectionField struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"` // text, integer, number, boolean, timestamp
+		Embedded bool   `json:"embedded"`
+		Required bool   `json:"required"`
+	}
+
+	// Collection is a user-defined document type with its own table and vector space.
+	Collection struct {
+		Name      string            `json:"name"`
+		Table     string            `json:"-"`
+		Fields    []CollectionField `json:"fields"`
+		Dimension int               `json:"dimension"`
+		Metric    string            `json:"metric"` // cosine, l2, inner_product
+		CreatedAt string            `json:"created_at"`
+		UpdatedAt string            `json:"updated_at"`
+	}
+
+	CollectionList struct {
+		Items []Collection `json:"collection"`
+		Count int          `json:"count"`
+	}
+
+	Document struct {
+		ID        string                 `json:"id"`
+		Data      map[string]interface{} `json:"data"`
+		Distance  float32                `json:"distance,omitempty"`
+		CreatedAt string                 `json:"created_at"`
+		UpdatedAt string                 `json:"updated_at"`
+	}
+
+	DocumentList struct {
+		Items []Document `json:"document"`
+		Count int        `json:"count"`
+	}
+
+	DocumentSearchRequest struct {
+		Collection string `json:"collection"`
+		Query      string `json:"search"`
+		Limit      int    `json:"limit"`
+	}
+)
@@ -0,0 +1,66 @@
+package entity
+
+// Modes of the duplicate check on create.
+const (
+	DedupOff    = "off"
+	DedupWarn   = "warn"
+	DedupReject = "reject"
+)
+
+type (
+	// MovieDuplicate is a stored movie that looks like the same film as
+	// another one. A similarity is zero when it was not measured.
+	MovieDuplicate struct {
+		ID               string  `json:"id"`
+		Slug             string  `json:"slug"`
+		NameUz           string  `json:"name_uz"`
+		NameRu           string  `json:"name_ru"`
+		NameEn           string  `json:"name_en"`
+		ReleaseYear      int     `json:"release_year"`
+		NameSimilarity   float64 `json:"name_similarity,omitempty"`
+		VectorSimilarity float64 `json:"vector_similarity,omitempty"`
+	}
+
+	// MovieDuplicateError is the 409 response of a create rejected as a
+	// likely duplicate.
+	MovieDuplicateError struct {
+		Message    string           `json:"message"`
+		Code       string           `json:"code"`
+		Duplicates []MovieDuplicate `json:"duplicates"`
+	}
+
+	// MovieDuplicatePair links two movies of a cluster that look alike.
+	MovieDuplicatePair struct {
+		A                string  `json:"a"`
+		B                string  `json:"b"`
+		NameSimilarity   float64 `json:"name_similarity,omitempty"`
+		VectorSimilarity float64 `json:"vector_similarity,omitempty"`
+	}
+
+	// MovieDuplicateCluster is a group of movies connected by look-alike
+	// pairs; Movies carry no similarity of their own.
+	MovieDuplicateCluster struct {
+		Movies []MovieDuplicate     `json:"movies"`
+		Pairs  []MovieDuplicatePair `json:"pairs"`
+	}
+
+	MovieDuplicateReport struct {
+		Clusters []MovieDuplicateCluster `json:"clusters"`
+		Count    int                     `json:"count"`
+		// Truncated is set when only the strongest pairs were clustered.
+		Truncated bool `json:"truncated,omitempty"`
+	}
+
+	MovieDuplicateReportRequest struct {
+		Limit int `json:"limit"`
+		// Rescan flags the vector look-alikes of every movie again first.
+		Rescan bool `json:"rescan"`
+	}
+
+	// MovieMergeRequest merges Duplicates into the movie ID.
+	MovieMergeRequest struct {
+		ID         string   `json:"-"`
+		Duplicates []string `json:"duplicates"`
+		Version    int      `json:"-"` // expected version of ID, 0 skips the check
+	}
+)
@@ -0,0 +1,18 @@
+package entity
+
+type (
+	Genre struct {
+		ID        string `json:"id"`
+		Slug      string `json:"slug"`
+		NameUz    string `json:"name_uz"`
+		NameRu    string `json:"name_ru"`
+		NameEn    string `json:"name_en"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	GenreList struct {
+		Items []Genre `json:"genre"`
+		Count int     `json:"count"`
+	}
+)
@@ -0,0 +1,14 @@
+package entity
+
+type (
+	// IdempotentResponse is the stored outcome of a write request sent with
+	// an Idempotency-Key header. Status is zero while the request that
+	// claimed the key is still being processed.
+	IdempotentResponse struct {
+		Key         string
+		RequestHash string
+		Status      int
+		Header      map[string]string
+		Body        []byte
+	}
+)
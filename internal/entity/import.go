@@ -0,0 +1,51 @@
+package entity
+
+import "io"
+
+// Outcomes of an imported line.
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportFailed    = "failed"
+)
+
+type (
+	MovieImportRequest struct {
+		// Source namespaces ExternalID, e.g. the catalog the upload comes from.
+		Source string `json:"source"`
+		Format string `json:"format"` // csv, ndjson, json or arrow
+		// Mapping maps movie fields to the upload columns they are read from.
+		// Fields without an entry are read from the column of the same name.
+		Mapping map[string]string `json:"mapping"`
+		Body    io.Reader         `json:"-"`
+	}
+
+	MovieExportRequest struct {
+		Format string    `json:"format"` // csv, ndjson or arrow
+		Writer io.Writer `json:"-"`
+	}
+
+	MovieImportLine struct {
+		Line       int    `json:"line"`
+		ExternalID string `json:"external_id,omitempty"`
+		ID         string `json:"id,omitempty"`
+		Status     string `json:"status"`
+		Error      string `json:"error,omitempty"`
+		// Restored is set when the embedding of the line was stored instead
+		// of being generated again.
+		Restored bool `json:"restored,omitempty"`
+	}
+
+	MovieImportReport struct {
+		Created   int `json:"created"`
+		Updated   int `json:"updated"`
+		Unchanged int `json:"unchanged"`
+		Failed    int `json:"failed"`
+		Restored  int `json:"restored"` // embeddings taken from the upload
+		// Error is set when the import stopped early; lines after the last
+		// reported one were not read.
+		Error string            `json:"error,omitempty"`
+		Lines []MovieImportLine `json:"lines"`
+	}
+)
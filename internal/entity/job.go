@@ -0,0 +1,59 @@
+package entity
+
+import "encoding/json"
+
+// Job states.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job kinds.
+const (
+	JobMovieImport     = "movie.import"
+	JobMovieReembed    = "movie.reembed"
+	JobMovieDuplicates = "movie.duplicates"
+)
+
+type (
+	Job struct {
+		ID      string          `json:"id"`
+		Kind    string          `json:"kind"`
+		State   string          `json:"state"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		// Error is the error of the last attempt; a job queued for a retry keeps it.
+		Error           string      `json:"error,omitempty"`
+		Progress        JobProgress `json:"progress"`
+		Attempts        int         `json:"attempts"`
+		MaxAttempts     int         `json:"max_attempts"`
+		CancelRequested bool        `json:"cancel_requested,omitempty"`
+		Actor           string      `json:"actor"`
+		RunAt           string      `json:"run_at"`
+		StartedAt       string      `json:"started_at,omitempty"`
+		FinishedAt      string      `json:"finished_at,omitempty"`
+		CreatedAt       string      `json:"created_at"`
+		UpdatedAt       string      `json:"updated_at"`
+
+		TenantID string `json:"-"`
+		// Input is uploaded data the job works on. It is only read when a
+		// worker claims the job.
+		Input []byte `json:"-"`
+		// UniqueKey, when set, keeps a second job with the same key from being
+		// queued while the first one is queued or running.
+		UniqueKey string `json:"-"`
+	}
+
+	JobProgress struct {
+		Done  int `json:"done"`
+		Total int `json:"total"` // zero when not known
+	}
+
+	JobList struct {
+		Items []Job `json:"jobs"`
+		Count int   `json:"count"`
+	}
+)
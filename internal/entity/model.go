@@ -3,6 +3,8 @@ package entity
 type Id struct {
 	ID   string `json:"id"`
 	Slug string `json:"slug"`
+	// Version, when set, must match the current version of the record.
+	Version int `json:"version,omitempty"`
 }
 
 type OrderBy struct {
@@ -12,8 +14,10 @@ type OrderBy struct {
 
 type Filter struct {
 	Column string `json:"column"`
-	Type   string `json:"type"` // eq, ne, gt, gte, lt, lte, search
-	Value  string `json:"value"`
+	Type   string `json:"type"` // eq, neq, gt, gte, lt, lte, search, in, not_in, is_null, between
+	// Value is a comma-separated list for in, not_in and between, and true
+	// or false (default true) for is_null.
+	Value string `json:"value"`
 }
 
 type GetListFilter struct {
@@ -21,6 +25,12 @@ type GetListFilter struct {
 	Limit   int       `json:"limit"`
 	Filters []Filter  `json:"filters"`
 	OrderBy []OrderBy `json:"order_by"`
+	// Cursor continues from a next or prev cursor of a previous page and
+	// takes precedence over Page. Only movie lists support it.
+	Cursor string `json:"cursor,omitempty"`
+	// Facets names the facets to count over the filtered list. Only movie
+	// lists support it.
+	Facets []string `json:"facets,omitempty"`
 }
 
 type UpdateFieldItem struct {
@@ -31,10 +41,12 @@ type UpdateFieldItem struct {
 type UpdateFieldRequest struct {
 	Filter []Filter          `json:"filter"`
 	Items  []UpdateFieldItem `json:"items"`
+	DryRun bool              `json:"dry_run"`
 }
 
 type RowsEffected struct {
 	RowsEffected int `json:"rows_effected"`
+	Reembedded   int `json:"reembedded,omitempty"` // queued for the embedding workers
 }
 
 type ErrorResponse struct {
@@ -1,19 +1,86 @@
 package entity
 
+// Patch formats accepted by MoviePatchRequest.
+const (
+	PatchMerge = "merge-patch" // RFC 7396
+	PatchJSON  = "json-patch"  // RFC 6902
+)
+
+// Embedding states of a movie.
+const (
+	EmbeddingPending = "pending"
+	EmbeddingReady   = "ready"
+	EmbeddingFailed  = "failed"
+)
+
+// Facets MovieList can report counts for.
+const (
+	FacetGenre    = "genre"    // by genre slug
+	FacetYear     = "year"     // by decade of release_year, "unknown" when it is not set
+	FacetLanguage = "language" // by language with a title
+)
+
 type (
 	Movie struct {
-		ID        string  `json:"id"`
-		NameUz    string  `json:"name_uz"`
-		NameRu    string  `json:"name_ru"`
-		NameEn    string  `json:"name_en"`
-		Embedding string  `json:"-"`
-		Distance  float32 `json:"distance"`
-		CreatedAt string  `json:"created_at"`
-		UpdatedAt string  `json:"updated_at"`
+		ID      string   `json:"id"`
+		Slug    string   `json:"slug"`
+		NameUz  string   `json:"name_uz"`
+		NameRu  string   `json:"name_ru"`
+		NameEn  string   `json:"name_en"`
+		Aliases []string `json:"aliases"`
+
+		DescriptionUz  string        `json:"description_uz"`
+		DescriptionRu  string        `json:"description_ru"`
+		DescriptionEn  string        `json:"description_en"`
+		ReleaseYear    int           `json:"release_year"`
+		RuntimeMinutes int           `json:"runtime_minutes"`
+		Country        string        `json:"country"`
+		PosterURL      string        `json:"poster_url"`
+		Genres         []Genre       `json:"genres"`
+		Cast           []MovieCredit `json:"cast"`
+
+		Embedding string `json:"-"`
+		// EmbeddingStatus is pending until the vector of the current content
+		// is stored. Search ranks movies that are not ready by full text only.
+		EmbeddingStatus string  `json:"embedding_status"`
+		EmbeddingError  string  `json:"embedding_error,omitempty"`
+		Distance        float32 `json:"distance"`
+		CreatedAt       string  `json:"created_at"`
+		UpdatedAt       string  `json:"updated_at"`
+		DeletedAt       string  `json:"deleted_at,omitempty"`
+		// ExternalSource and ExternalID identify the movie in the catalog it
+		// was imported from. Only imports set them.
+		ExternalSource string `json:"external_source,omitempty"`
+		ExternalID     string `json:"external_id,omitempty"`
+		// Version grows with every change. On update a non-zero version must
+		// match the stored one, like an If-Match header.
+		Version int `json:"version"`
+
+		Explain *MovieHitExplain `json:"explain,omitempty"`
+		// Duplicates lists stored movies that look like a created one.
+		Duplicates []MovieDuplicate `json:"duplicates,omitempty"`
+	}
+
+	// MovieCredit links a person to a movie in a role such as actor or director.
+	MovieCredit struct {
+		PersonID  string `json:"person_id"`
+		FullName  string `json:"full_name"`
+		Role      string `json:"role"`
+		Character string `json:"character"`
+		Position  int    `json:"position"`
+	}
+
+	// MoviePatchRequest carries a patch document for a single movie.
+	MoviePatchRequest struct {
+		ID      string
+		Patch   []byte
+		Format  string // PatchMerge or PatchJSON
+		Version int    // expected version, 0 skips the check
 	}
 
 	MovieSingleRequest struct {
 		ID     string `json:"id"`
+		Slug   string `json:"slug"` // current or earlier slug of the movie
 		NameUz string `json:"name_uz"`
 		NameRu string `json:"name_ru"`
 		NameEn string `json:"name_en"`
@@ -22,5 +89,75 @@ type (
 	MovieList struct {
 		Items []Movie `json:"movie"`
 		Count int     `json:"count"`
+
+		// NextCursor and PrevCursor are opaque tokens for the adjacent pages;
+		// they are empty at either end.
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+
+		Facets  *MovieFacets   `json:"facets,omitempty"`
+		Explain *SearchExplain `json:"explain,omitempty"`
+	}
+
+	// MovieFacets holds the counts of the requested facets over all movies
+	// matching a list or search request, not only the returned page.
+	MovieFacets struct {
+		Genres    []FacetCount `json:"genre,omitempty"`
+		Years     []FacetCount `json:"year,omitempty"`
+		Languages []FacetCount `json:"language,omitempty"`
+	}
+
+	FacetCount struct {
+		Value string `json:"value"`
+		Label string `json:"label,omitempty"`
+		Count int    `json:"count"`
+	}
+
+	MovieSearchRequest struct {
+		Query   string   `json:"search"`
+		Limit   int      `json:"limit"`
+		Cursor  string   `json:"cursor,omitempty"`
+		Facets  []string `json:"facets,omitempty"`
+		Explain bool     `json:"explain"`
+	}
+
+	// MovieHitExplain describes how a single search hit was scored.
+	MovieHitExplain struct {
+		Distance     float32 `json:"distance"`
+		TextRank     float32 `json:"text_rank"`
+		Score        float32 `json:"score"`
+		MatchedField string  `json:"matched_field"`
+		RerankScore  float32 `json:"rerank_score,omitempty"`
+		FusionRank   int     `json:"fusion_rank"`
+	}
+
+	// SearchExplain describes how the search query itself was executed.
+	SearchExplain struct {
+		Model        string   `json:"model"`
+		Dimensions   int      `json:"dimensions"`
+		VectorWeight float64  `json:"vector_weight"`
+		TextWeight   float64  `json:"text_weight"`
+		Plan         string   `json:"plan"`
+		PlanCost     float64  `json:"plan_cost"`
+		Expansions   []string `json:"expansions,omitempty"`
+		Reranker     string   `json:"reranker,omitempty"`
+		RerankError  string   `json:"rerank_error,omitempty"`
+	}
+
+	MovieSuggestRequest struct {
+		Query string `json:"q"`
+		Lang  string `json:"lang"` // uz, en, ru or empty for all
+		Limit int    `json:"limit"`
+	}
+
+	MovieSuggestion struct {
+		ID    string  `json:"id"`
+		Title string  `json:"title"`
+		Lang  string  `json:"lang"`
+		Score float32 `json:"score"`
+	}
+
+	MovieSuggestList struct {
+		Items []MovieSuggestion `json:"items"`
 	}
 )
@@ -0,0 +1,15 @@
+package entity
+
+type (
+	Person struct {
+		ID        string `json:"id"`
+		FullName  string `json:"full_name"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	PersonList struct {
+		Items []Person `json:"person"`
+		Count int      `json:"count"`
+	}
+)
@@ -0,0 +1,34 @@
+package entity
+
+type (
+	// MovieRevision records one change of a movie.
+	MovieRevision struct {
+		MovieID   string                 `json:"movie_id"`
+		Revision  int                    `json:"revision"`
+		Action    string                 `json:"action"` // create, update, update_field, delete, restore, revert
+		Actor     string                 `json:"actor"`
+		Diff      map[string]FieldChange `json:"diff"`
+		CreatedAt string                 `json:"created_at"`
+	}
+
+	FieldChange struct {
+		Old interface{} `json:"old"`
+		New interface{} `json:"new"`
+	}
+
+	MovieRevisionList struct {
+		Items []MovieRevision `json:"revision"`
+		Count int             `json:"count"`
+	}
+
+	MovieHistoryRequest struct {
+		MovieID string `json:"movie_id"`
+		Page    int    `json:"page"`
+		Limit   int    `json:"limit"`
+	}
+
+	MovieRevertRequest struct {
+		MovieID  string `json:"movie_id"`
+		Revision int    `json:"revision"`
+	}
+)
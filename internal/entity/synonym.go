@@ -0,0 +1,18 @@
+package entity
+
+type (
+	// Synonym expands a search term, e.g. an abbreviation or nickname, into
+	// the phrases it stands for.
+	Synonym struct {
+		ID         string   `json:"id"`
+		Term       string   `json:"term"`
+		Expansions []string `json:"expansions"`
+		CreatedAt  string   `json:"created_at"`
+		UpdatedAt  string   `json:"updated_at"`
+	}
+
+	SynonymList struct {
+		Items []Synonym `json:"synonym"`
+		Count int       `json:"count"`
+	}
+)
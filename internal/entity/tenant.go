@@ -0,0 +1,24 @@
+package entity
+
+type (
+	Tenant struct {
+		ID                string `json:"id"`
+		Name              string `json:"name"`
+		MaxMovies         int    `json:"max_movies"`          // 0 means unlimited
+		MaxCollections    int    `json:"max_collections"`     // 0 means unlimited
+		RequestsPerMinute int    `json:"requests_per_minute"` // 0 means unlimited
+		CreatedAt         string `json:"created_at"`
+		UpdatedAt         string `json:"updated_at"`
+	}
+
+	// TenantWithKey is returned once, when a tenant is created; only a hash of the key is stored.
+	TenantWithKey struct {
+		Tenant
+		ApiKey string `json:"api_key"`
+	}
+
+	TenantList struct {
+		Items []Tenant `json:"tenant"`
+		Count int      `json:"count"`
+	}
+)
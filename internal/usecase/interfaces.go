@@ -3,6 +3,8 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/abdulazizax/ai-embedding/internal/entity"
 )
@@ -16,8 +18,95 @@ type (
 		GetSingle(ctx context.Context, req entity.MovieSingleRequest) (entity.Movie, error)
 		GetList(ctx context.Context, req entity.GetListFilter) (entity.MovieList, error)
 		Update(ctx context.Context, req entity.Movie) (entity.Movie, error)
+		UpsertExternal(ctx context.Context, req entity.Movie) (entity.Movie, bool, error)
+		Patch(ctx context.Context, req entity.MoviePatchRequest) (entity.Movie, error)
 		Delete(ctx context.Context, req entity.Id) error
+		GetTrash(ctx context.Context, req entity.GetListFilter) (entity.MovieList, error)
+		Restore(ctx context.Context, req entity.Id) (entity.Movie, error)
+		PurgeTrash(ctx context.Context, retention time.Duration) (int, error)
+		GetHistory(ctx context.Context, req entity.MovieHistoryRequest) (entity.MovieRevisionList, error)
+		Revert(ctx context.Context, req entity.MovieRevertRequest) (entity.Movie, error)
 		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
-		Search(ctx context.Context, req entity.MovieSingleRequest) (entity.MovieList, error)
+		Import(ctx context.Context, req entity.MovieImportRequest) (entity.MovieImportReport, error)
+		Export(ctx context.Context, req entity.MovieExportRequest) (int, error)
+		FindDuplicates(ctx context.Context, movie entity.Movie) ([]entity.MovieDuplicate, error)
+		DuplicateReport(ctx context.Context, req entity.MovieDuplicateReportRequest) (entity.MovieDuplicateReport, error)
+		Merge(ctx context.Context, req entity.MovieMergeRequest) (entity.Movie, error)
+		Search(ctx context.Context, req entity.MovieSearchRequest) (entity.MovieList, error)
+		Suggest(ctx context.Context, req entity.MovieSuggestRequest) (entity.MovieSuggestList, error)
+		ReembedStale(ctx context.Context) (int, error)
+		ProcessEmbeddings(ctx context.Context) (int, error)
+	}
+
+	// GenreRepo -.
+	GenreRepoI interface {
+		Create(ctx context.Context, req entity.Genre) (entity.Genre, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Genre, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.GenreList, error)
+		Update(ctx context.Context, req entity.Genre) (entity.Genre, error)
+		Delete(ctx context.Context, req entity.Id) error
+	}
+
+	// PersonRepo -.
+	PersonRepoI interface {
+		Create(ctx context.Context, req entity.Person) (entity.Person, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Person, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.PersonList, error)
+		Update(ctx context.Context, req entity.Person) (entity.Person, error)
+		Delete(ctx context.Context, req entity.Id) error
+	}
+
+	// CollectionRepo -.
+	CollectionRepoI interface {
+		Create(ctx context.Context, req entity.Collection) (entity.Collection, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Collection, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.CollectionList, error)
+		Delete(ctx context.Context, req entity.Id) error
+		CreateDocument(ctx context.Context, collection string, req entity.Document) (entity.Document, error)
+		GetDocument(ctx context.Context, collection string, req entity.Id) (entity.Document, error)
+		GetDocuments(ctx context.Context, collection string, req entity.GetListFilter) (entity.DocumentList, error)
+		UpdateDocument(ctx context.Context, collection string, req entity.Document) (entity.Document, error)
+		DeleteDocument(ctx context.Context, collection string, req entity.Id) error
+		SearchDocuments(ctx context.Context, req entity.DocumentSearchRequest) (entity.DocumentList, error)
+	}
+
+	// SynonymRepo -.
+	SynonymRepoI interface {
+		Create(ctx context.Context, req entity.Synonym) (entity.Synonym, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Synonym, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.SynonymList, error)
+		Update(ctx context.Context, req entity.Synonym) (entity.Synonym, error)
+		Delete(ctx context.Context, req entity.Id) error
+		Expand(ctx context.Context, query string) ([]string, error)
+	}
+
+	// TenantRepo -.
+	TenantRepoI interface {
+		Create(ctx context.Context, req entity.Tenant) (entity.TenantWithKey, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Tenant, error)
+		GetByApiKey(ctx context.Context, apiKey string) (entity.Tenant, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.TenantList, error)
+		Update(ctx context.Context, req entity.Tenant) (entity.Tenant, error)
+	}
+
+	// JobRepo -.
+	JobRepoI interface {
+		Enqueue(ctx context.Context, req entity.Job) (entity.Job, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Job, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.JobList, error)
+		Cancel(ctx context.Context, req entity.Id) (entity.Job, error)
+		Claim(ctx context.Context, worker string, kinds []string, lease time.Duration) (entity.Job, bool, error)
+		Heartbeat(ctx context.Context, id, worker string, progress entity.JobProgress, lease time.Duration) (bool, error)
+		Finish(ctx context.Context, id, worker, state string, result json.RawMessage, message string) error
+		Retry(ctx context.Context, id, worker, message string, result json.RawMessage, delay time.Duration) error
+		Requeue(ctx context.Context, id, worker string) error
+	}
+
+	// IdempotencyRepo -.
+	IdempotencyRepoI interface {
+		Claim(ctx context.Context, key, requestHash string, lock time.Duration) (entity.IdempotentResponse, bool, error)
+		Complete(ctx context.Context, req entity.IdempotentResponse, ttl time.Duration) error
+		Release(ctx context.Context, key, requestHash string) error
+		PurgeExpired(ctx context.Context) (int, error)
 	}
 )
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+// registerJobs sets the handlers of the job kinds the application queues.
+func registerJobs(worker *Worker, uc *UseCase) {
+	worker.Register(entity.JobMovieImport, importMoviesJob(uc.MovieRepo))
+	worker.Register(entity.JobMovieReembed, reembedMoviesJob(uc.MovieRepo))
+	worker.Register(entity.JobMovieDuplicates, duplicateReportJob(uc.MovieRepo))
+}
+
+// importMoviesJob imports the upload stored as job input. The payload is
+// the entity.MovieImportRequest without body. An import that stopped early
+// fails the attempt with its partial report as result; lines are upserted by
+// external id, so a retried import picks up where the failed attempt stopped.
+func importMoviesJob(movies MovieRepoI) JobHandler {
+	return func(ctx context.Context, job entity.Job) (interface{}, error) {
+		var req entity.MovieImportRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			return nil, err
+		}
+		req.Body = bytes.NewReader(job.Input)
+
+		report, err := movies.Import(ctx, req)
+		if err == nil {
+			// Import reports a stopped import in the report; cancellation
+			// still has to reach the worker.
+			err = ctx.Err()
+		}
+		if err == nil && report.Error != "" {
+			err = errors.New(report.Error)
+		}
+
+		return report, err
+	}
+}
+
+// reembedMoviesJob embeds again the movies embedded with an older document
+// template.
+func reembedMoviesJob(movies MovieRepoI) JobHandler {
+	return func(ctx context.Context, job entity.Job) (interface{}, error) {
+		n, err := movies.ReembedStale(ctx)
+		return map[string]int{"reembedded": n}, err
+	}
+}
+
+// duplicateReportJob builds the duplicate report of the tenant. The payload
+// is the entity.MovieDuplicateReportRequest.
+func duplicateReportJob(movies MovieRepoI) JobHandler {
+	return func(ctx context.Context, job entity.Job) (interface{}, error) {
+		var req entity.MovieDuplicateReportRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			return nil, err
+		}
+
+		return movies.DuplicateReport(ctx, req)
+	}
+}
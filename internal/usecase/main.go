@@ -5,17 +5,38 @@ import (
 	"github.com/abdulazizax/ai-embedding/internal/usecase/repo"
 	"github.com/abdulazizax/ai-embedding/pkg/logger"
 	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/rerank"
 	openai "github.com/sashabaranov/go-openai"
 )
 
 // UseCase -.
 type UseCase struct {
-	MovieRepo MovieRepoI
+	MovieRepo       MovieRepoI
+	GenreRepo       GenreRepoI
+	PersonRepo      PersonRepoI
+	SynonymRepo     SynonymRepoI
+	CollectionRepo  CollectionRepoI
+	TenantRepo      TenantRepoI
+	JobRepo         JobRepoI
+	IdempotencyRepo IdempotencyRepoI
+	Worker          *Worker
 }
 
 // New -.
-func New(openaiClient *openai.Client, pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *UseCase {
-	return &UseCase{
-		MovieRepo: repo.NewMovieRepo(openaiClient, pg, config, logger),
+func New(openaiClient *openai.Client, reranker rerank.Interface, pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *UseCase {
+	uc := &UseCase{
+		MovieRepo:       repo.NewMovieRepo(openaiClient, reranker, pg, config, logger),
+		GenreRepo:       repo.NewGenreRepo(pg, config, logger),
+		PersonRepo:      repo.NewPersonRepo(pg, config, logger),
+		SynonymRepo:     repo.NewSynonymRepo(pg, config, logger),
+		CollectionRepo:  repo.NewCollectionRepo(openaiClient, pg, config, logger),
+		TenantRepo:      repo.NewTenantRepo(pg, config, logger),
+		JobRepo:         repo.NewJobRepo(pg, config, logger),
+		IdempotencyRepo: repo.NewIdempotencyRepo(pg, config, logger),
 	}
+
+	uc.Worker = NewWorker(uc.JobRepo, config, logger)
+	registerJobs(uc.Worker, uc)
+
+	return uc
 }
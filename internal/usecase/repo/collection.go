@@ -0,0 +1,715 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const _maxCollectionDimension = 2000
+
+var (
+	identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,47}$`)
+
+	// collectionFieldTypes maps field types to the column types backing them.
+	collectionFieldTypes = map[string]string{
+		"text":      "TEXT",
+		"integer":   "BIGINT",
+		"number":    "DOUBLE PRECISION",
+		"boolean":   "BOOLEAN",
+		"timestamp": "TIMESTAMPTZ",
+	}
+
+	// collectionMetrics maps distance metrics to their pgvector operators.
+	collectionMetrics = map[string]string{
+		"cosine":        "<=>",
+		"l2":            "<->",
+		"inner_product": "<#>",
+	}
+
+	// collectionIndexOps maps distance metrics to the pgvector operator
+	// classes that index them.
+	collectionIndexOps = map[string]string{
+		"cosine":        "vector_cosine_ops",
+		"l2":            "vector_l2_ops",
+		"inner_product": "vector_ip_ops",
+	}
+
+	reservedFieldNames = map[string]struct{}{
+		"id":         {},
+		"embedding":  {},
+		"created_at": {},
+		"updated_at": {},
+	}
+)
+
+type CollectionRepo struct {
+	openaiClient *openai.Client
+	pg           *postgres.Postgres
+	config       *config.Config
+	logger       *logger.Logger
+}
+
+// New -.
+func NewCollectionRepo(openaiClient *openai.Client, pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *CollectionRepo {
+	return &CollectionRepo{
+		openaiClient: openaiClient,
+		pg:           pg,
+		config:       config,
+		logger:       logger,
+	}
+}
+
+// Create stores the collection definition and creates its document table in one transaction.
+func (r *CollectionRepo) Create(ctx context.Context, req entity.Collection) (entity.Collection, error) {
+	_, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	if req.Metric == "" {
+		req.Metric = "cosine"
+	}
+
+	err = validateCollection(req)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	// Documents and queries are embedded with the configured model.
+	err = checkModelDimensions(r.config.OpenAI.EmbeddingModel, req.Dimension)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	// Tables are named by a uuid of their own, since tenants reuse collection
+	// names and tenant ids may share any prefix.
+	tenantID := tenant.ID(ctx)
+	req.Table = "coll_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	fields, err := json.Marshal(req.Fields)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("collections").
+		Columns(`tenant_id, name, table_name, fields, dimension, metric`).
+		Values(tenantID, req.Name, req.Table, fields, req.Dimension, req.Metric).
+		Suffix("RETURNING created_at, updated_at").ToSql()
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = checkQuota(ctx, tx, "collections", "max_collections"); err != nil {
+		return entity.Collection{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	columns := make([]string, 0, len(req.Fields))
+	for _, field := range req.Fields {
+		column := pgx.Identifier{field.Name}.Sanitize() + " " + collectionFieldTypes[field.Type]
+		if field.Required {
+			column += " NOT NULL"
+		}
+		columns = append(columns, column)
+	}
+
+	ddl := fmt.Sprintf(`CREATE TABLE %s (
+		id UUID PRIMARY KEY,
+		%s,
+		embedding VECTOR(%d),
+		created_at timestamp NOT NULL DEFAULT 'now()',
+		updated_at timestamp NOT NULL DEFAULT 'now()'
+	)`, collectionTable(req.Table), strings.Join(columns, ",\n\t\t"), req.Dimension)
+
+	if _, err = tx.Exec(ctx, ddl); err != nil {
+		return entity.Collection{}, err
+	}
+
+	// The index only serves searches under the collection's metric; Postgres
+	// picks its name, as table names may be too long to extend.
+	ddl = fmt.Sprintf(`CREATE INDEX ON %s USING hnsw (embedding %s)`, collectionTable(req.Table), collectionIndexOps[req.Metric])
+	if _, err = tx.Exec(ctx, ddl); err != nil {
+		return entity.Collection{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Collection{}, err
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return req, nil
+}
+
+func (r *CollectionRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Collection, error) {
+	var (
+		response             = entity.Collection{}
+		fields               []byte
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`name, table_name, fields, dimension, metric, created_at, updated_at`).
+		From("collections").
+		Where("name = ?", req.ID).
+		Where(scope).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.Name, &response.Table, &fields, &response.Dimension, &response.Metric, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Collection{}, err
+	}
+
+	if err = json.Unmarshal(fields, &response.Fields); err != nil {
+		return entity.Collection{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *CollectionRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.CollectionList, error) {
+	var (
+		response             = entity.CollectionList{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`name, table_name, fields, dimension, metric, created_at, updated_at`).
+		From("collections").
+		Where(scope)
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, collectionFields, req)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, scope)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item   entity.Collection
+			fields []byte
+		)
+		err = rows.Scan(&item.Name, &item.Table, &fields, &item.Dimension, &item.Metric, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		if err = json.Unmarshal(fields, &item.Fields); err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("collections").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Delete drops the collection together with all of its documents.
+func (r *CollectionRepo) Delete(ctx context.Context, req entity.Id) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	qeury, args, err := r.pg.Builder.Delete("collections").Where("name = ?", req.ID).Where(scope).
+		Suffix("RETURNING table_name").ToSql()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var table string
+	if err = tx.QueryRow(ctx, qeury, args...).Scan(&table); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DROP TABLE IF EXISTS "+collectionTable(table)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *CollectionRepo) CreateDocument(ctx context.Context, collection string, req entity.Document) (entity.Document, error) {
+	coll, err := r.GetSingle(ctx, entity.Id{ID: collection})
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	values, err := documentValues(coll, req.Data)
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	embedding, err := r.embedDocument(ctx, coll, req.Data)
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	values[`"id"`] = uuid.NewString()
+	values["embedding"] = embedding
+
+	qeury, args, err := r.pg.Builder.Insert(collectionTable(coll.Table)).SetMap(values).
+		Suffix("RETURNING id").ToSql()
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	var id string
+	if err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&id); err != nil {
+		return entity.Document{}, err
+	}
+
+	return r.getDocument(ctx, coll, id)
+}
+
+func (r *CollectionRepo) GetDocument(ctx context.Context, collection string, req entity.Id) (entity.Document, error) {
+	coll, err := r.GetSingle(ctx, entity.Id{ID: collection})
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	return r.getDocument(ctx, coll, req.ID)
+}
+
+func (r *CollectionRepo) GetDocuments(ctx context.Context, collection string, req entity.GetListFilter) (entity.DocumentList, error) {
+	response := entity.DocumentList{}
+
+	coll, err := r.GetSingle(ctx, entity.Id{ID: collection})
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(documentColumns(coll)).
+		From(collectionTable(coll.Table))
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, documentFields(coll), req)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanDocument(coll, rows)
+		if err != nil {
+			return response, err
+		}
+
+		response.Items = append(response.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From(collectionTable(coll.Table)).Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// UpdateDocument replaces all fields of the document and embeds it again.
+func (r *CollectionRepo) UpdateDocument(ctx context.Context, collection string, req entity.Document) (entity.Document, error) {
+	coll, err := r.GetSingle(ctx, entity.Id{ID: collection})
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	values, err := documentValues(coll, req.Data)
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	embedding, err := r.embedDocument(ctx, coll, req.Data)
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	values["embedding"] = embedding
+	values["updated_at"] = "now()"
+
+	qeury, args, err := r.pg.Builder.Update(collectionTable(coll.Table)).SetMap(values).
+		Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	if n.RowsAffected() == 0 {
+		return entity.Document{}, pgx.ErrNoRows
+	}
+
+	return r.getDocument(ctx, coll, req.ID)
+}
+
+func (r *CollectionRepo) DeleteDocument(ctx context.Context, collection string, req entity.Id) error {
+	coll, err := r.GetSingle(ctx, entity.Id{ID: collection})
+	if err != nil {
+		return err
+	}
+
+	qeury, args, err := r.pg.Builder.Delete(collectionTable(coll.Table)).Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	if n.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SearchDocuments returns the documents nearest to the query under the collection's metric.
+func (r *CollectionRepo) SearchDocuments(ctx context.Context, req entity.DocumentSearchRequest) (entity.DocumentList, error) {
+	response := entity.DocumentList{}
+
+	coll, err := r.GetSingle(ctx, entity.Id{ID: req.Collection})
+	if err != nil {
+		return response, err
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+
+	vectors, err := createEmbeddings(ctx, r.openaiClient, r.config.OpenAI.EmbeddingModel, []string{req.Query}, coll.Dimension)
+	if err != nil {
+		return response, fmt.Errorf("CollectionRepo - SearchDocuments - %w", err)
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(documentColumns(coll) + ", embedding " + collectionMetrics[coll.Metric] + " ? AS distance").
+		From(collectionTable(coll.Table)).
+		Where("embedding IS NOT NULL").
+		OrderBy("distance").
+		Limit(uint64(req.Limit)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	// The vector is the first placeholder, in the select list.
+	rows, err := r.pg.Pool.Query(ctx, qeury, append([]interface{}{formatVectorLiteral(vectors[0])}, args...)...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanDocument(coll, rows)
+		if err != nil {
+			return response, err
+		}
+
+		response.Items = append(response.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	response.Count = len(response.Items)
+
+	return response, nil
+}
+
+func (r *CollectionRepo) getDocument(ctx context.Context, coll entity.Collection, id string) (entity.Document, error) {
+	qeury, args, err := r.pg.Builder.
+		Select(documentColumns(coll)).
+		From(collectionTable(coll.Table)).
+		Where("id = ?", id).ToSql()
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return entity.Document{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return entity.Document{}, err
+		}
+		return entity.Document{}, pgx.ErrNoRows
+	}
+
+	return scanDocument(coll, rows)
+}
+
+// embedDocument embeds the embedded fields of data, one "name: value" line each.
+func (r *CollectionRepo) embedDocument(ctx context.Context, coll entity.Collection, data map[string]interface{}) (string, error) {
+	lines := make([]string, 0, len(coll.Fields))
+
+	for _, field := range coll.Fields {
+		value, ok := data[field.Name]
+		if !field.Embedded || !ok || value == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %v", field.Name, value))
+	}
+
+	if len(lines) == 0 {
+		return "", fmt.Errorf(config.ErrorBadRequest + "document has no embedded fields set")
+	}
+
+	vectors, err := createEmbeddings(ctx, r.openaiClient, r.config.OpenAI.EmbeddingModel, []string{strings.Join(lines, "\n")}, coll.Dimension)
+	if err != nil {
+		return "", fmt.Errorf("CollectionRepo - embedDocument - %w", err)
+	}
+
+	return formatVectorLiteral(vectors[0]), nil
+}
+
+func validateCollection(coll entity.Collection) error {
+	if !identifierPattern.MatchString(coll.Name) {
+		return fmt.Errorf(config.ErrorBadRequest+"invalid collection name %q", coll.Name)
+	}
+	if coll.Dimension <= 0 || coll.Dimension > _maxCollectionDimension {
+		return fmt.Errorf(config.ErrorBadRequest+"dimension must be between 1 and %d", _maxCollectionDimension)
+	}
+	if _, ok := collectionMetrics[coll.Metric]; !ok {
+		return fmt.Errorf(config.ErrorBadRequest+"unknown metric %q", coll.Metric)
+	}
+	if len(coll.Fields) == 0 {
+		return fmt.Errorf(config.ErrorBadRequest + "collection requires at least one field")
+	}
+
+	var (
+		seen     = map[string]struct{}{}
+		embedded bool
+	)
+
+	for _, field := range coll.Fields {
+		if !identifierPattern.MatchString(field.Name) {
+			return fmt.Errorf(config.ErrorBadRequest+"invalid field name %q", field.Name)
+		}
+		if _, ok := reservedFieldNames[field.Name]; ok {
+			return fmt.Errorf(config.ErrorBadRequest+"field name %q is reserved", field.Name)
+		}
+		if _, ok := seen[field.Name]; ok {
+			return fmt.Errorf(config.ErrorBadRequest+"duplicate field %q", field.Name)
+		}
+		if _, ok := collectionFieldTypes[field.Type]; !ok {
+			return fmt.Errorf(config.ErrorBadRequest+"unknown type %q of field %q", field.Type, field.Name)
+		}
+
+		seen[field.Name] = struct{}{}
+		embedded = embedded || field.Embedded
+	}
+
+	if !embedded {
+		return fmt.Errorf(config.ErrorBadRequest + "collection requires at least one embedded field")
+	}
+
+	return nil
+}
+
+func collectionTable(table string) string {
+	return pgx.Identifier{table}.Sanitize()
+}
+
+// documentColumns lists the columns scanDocument reads, in order.
+func documentColumns(coll entity.Collection) string {
+	columns := []string{"id::text", "created_at", "updated_at"}
+
+	for _, field := range coll.Fields {
+		columns = append(columns, pgx.Identifier{field.Name}.Sanitize())
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+func scanDocument(coll entity.Collection, rows pgx.Rows) (entity.Document, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return entity.Document{}, err
+	}
+
+	document := entity.Document{
+		ID:   values[0].(string),
+		Data: make(map[string]interface{}, len(coll.Fields)),
+	}
+
+	if createdAt, ok := values[1].(time.Time); ok {
+		document.CreatedAt = createdAt.Format(time.RFC3339)
+	}
+	if updatedAt, ok := values[2].(time.Time); ok {
+		document.UpdatedAt = updatedAt.Format(time.RFC3339)
+	}
+
+	for i, field := range coll.Fields {
+		document.Data[field.Name] = values[3+i]
+	}
+
+	if len(values) > 3+len(coll.Fields) {
+		if distance, ok := values[3+len(coll.Fields)].(float64); ok {
+			document.Distance = float32(distance)
+		}
+	}
+
+	return document, nil
+}
+
+// documentValues validates data against the collection fields and converts it
+// to column values keyed by quoted column name. Unknown fields are rejected.
+func documentValues(coll entity.Collection, data map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(coll.Fields))
+	known := make(map[string]struct{}, len(coll.Fields))
+
+	for _, field := range coll.Fields {
+		known[field.Name] = struct{}{}
+
+		value, err := coerceFieldValue(field, data[field.Name])
+		if err != nil {
+			return nil, err
+		}
+
+		values[pgx.Identifier{field.Name}.Sanitize()] = value
+	}
+
+	for name := range data {
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"unknown field %q", name)
+		}
+	}
+
+	return values, nil
+}
+
+func coerceFieldValue(field entity.CollectionField, value interface{}) (interface{}, error) {
+	if value == nil {
+		if field.Required {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"field %q is required", field.Name)
+		}
+		return nil, nil
+	}
+
+	invalid := fmt.Errorf(config.ErrorBadRequest+"field %q must be of type %s", field.Name, field.Type)
+
+	switch field.Type {
+	case "text":
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case "integer":
+		if v, ok := value.(float64); ok && v == math.Trunc(v) {
+			return int64(v), nil
+		}
+	case "number":
+		if v, ok := value.(float64); ok {
+			return v, nil
+		}
+	case "boolean":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case "timestamp":
+		if v, ok := value.(string); ok {
+			t, err := time.Parse(time.RFC3339, v)
+			if err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	return nil, invalid
+}
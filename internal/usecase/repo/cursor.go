@@ -0,0 +1,246 @@
+package repo
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+// keysetKey is one expression of a keyset ordering. The last key of an
+// ordering must be unique, ties would otherwise repeat or skip rows.
+type keysetKey struct {
+	expr string
+	desc bool
+}
+
+// cursor is the payload of a pagination token: the sort key of the row the
+// page starts after (or before, for Prev) and the fingerprint of the query
+// it was issued for. Search pages through reranked windows of hits; its
+// cursors name the row the window starts after, none for the first window,
+// and the Offset of the page inside the window.
+type cursor struct {
+	Scope  string    `json:"s"`
+	Values []*string `json:"v"`
+	Prev   bool      `json:"p,omitempty"`
+	Offset int       `json:"o,omitempty"`
+}
+
+// cursorCodec signs cursors so clients cannot forge sort keys.
+type cursorCodec struct {
+	secret []byte
+}
+
+func newCursorCodec(secret string) (*cursorCodec, error) {
+	if secret != "" {
+		return &cursorCodec{secret: []byte(secret)}, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return &cursorCodec{secret: key}, nil
+}
+
+func (c *cursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload)) //nolint:errcheck
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *cursorCodec) encode(cur cursor) (string, error) {
+	body, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + c.sign(payload), nil
+}
+
+// decode verifies a token and checks it belongs to the query with the given
+// scope and number of sort keys.
+func (c *cursorCodec) decode(token, scope string, keys int) (cursor, error) {
+	var cur cursor
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return cur, fmt.Errorf(config.ErrorBadRequest + "invalid cursor")
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cur, fmt.Errorf(config.ErrorBadRequest + "invalid cursor")
+	}
+
+	// Only a forward cursor may start at the beginning.
+	if err = json.Unmarshal(body, &cur); err != nil || len(cur.Values) != keys && (len(cur.Values) != 0 || cur.Prev) || cur.Offset < 0 {
+		return cur, fmt.Errorf(config.ErrorBadRequest + "invalid cursor")
+	}
+
+	if cur.Scope != scope {
+		return cur, fmt.Errorf(config.ErrorBadRequest + "cursor was issued for different filters or sorting")
+	}
+
+	return cur, nil
+}
+
+// cursorScope fingerprints the parts of a query a cursor is only valid for.
+func cursorScope(parts ...interface{}) string {
+	body, _ := json.Marshal(parts) //nolint:errcheck
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:8])
+}
+
+// sortKeys validates an ordering against a registry.
+func sortKeys(fields fieldRegistry, orderBy []entity.OrderBy) ([]keysetKey, error) {
+	var keys []keysetKey
+
+	for _, e := range orderBy {
+		f, ok := fields[e.Column]
+		if !ok || !f.sortable {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"cannot sort by %q", e.Column)
+		}
+
+		order, err := sortOrder(e.Order)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, keysetKey{expr: f.sql(e.Column), desc: order == "desc"})
+	}
+
+	return keys, nil
+}
+
+// keysetOrder returns the ORDER BY clauses of keys, reversed when paging
+// backwards. Postgres puts NULLs last in ascending and first in descending
+// order, so reversing the direction also reverses the position of NULLs.
+func keysetOrder(keys []keysetKey, backward bool) []string {
+	clauses := make([]string, len(keys))
+	for i, key := range keys {
+		if key.desc != backward {
+			clauses[i] = key.expr + " DESC"
+		} else {
+			clauses[i] = key.expr + " ASC"
+		}
+	}
+
+	return clauses
+}
+
+// keysetColumns selects the sort keys as text, which round-trips every
+// sortable type without losing precision.
+func keysetColumns(keys []keysetKey) []string {
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = "(" + key.expr + ")::text"
+	}
+
+	return columns
+}
+
+// keysetCondition selects the rows after values in the order of keys, or
+// before them when paging backwards:
+//
+//	k1 > v1 OR (k1 = v1 AND k2 > v2) OR ...
+//
+// Without values every row comes after.
+func keysetCondition(keys []keysetKey, values []*string, backward bool) squirrel.Sqlizer {
+	if len(values) == 0 {
+		return squirrel.Expr("TRUE")
+	}
+
+	or := squirrel.Or{}
+	equal := squirrel.And{}
+
+	for i, key := range keys {
+		desc := key.desc != backward
+		value := values[i]
+
+		var after squirrel.Sqlizer
+		switch {
+		case value == nil && desc:
+			after = squirrel.Expr(key.expr + " IS NOT NULL")
+		case value == nil:
+			// NULLs come last, nothing sorts after them.
+		case desc:
+			after = squirrel.Expr(key.expr+" < ?", *value)
+		default:
+			after = squirrel.Or{squirrel.Expr(key.expr+" > ?", *value), squirrel.Expr(key.expr + " IS NULL")}
+		}
+
+		if after != nil {
+			or = append(or, append(append(squirrel.And{}, equal...), after))
+		}
+
+		if value == nil {
+			equal = append(equal, squirrel.Expr(key.expr+" IS NULL"))
+		} else {
+			equal = append(equal, squirrel.Expr(key.expr+" = ?", *value))
+		}
+	}
+
+	if len(or) == 0 {
+		return squirrel.Expr("FALSE")
+	}
+
+	return or
+}
+
+// numberPlaceholders rewrites the ? placeholders of a squirrel fragment to
+// $n, starting at $start, for use in hand-written queries.
+func numberPlaceholders(sql string, start int) string {
+	var b strings.Builder
+	for _, ch := range sql {
+		if ch == '?' {
+			fmt.Fprintf(&b, "$%d", start)
+			start++
+			continue
+		}
+		b.WriteRune(ch)
+	}
+
+	return b.String()
+}
+
+// links returns the cursors of the pages around a window. keys are the sort
+// keys of the window in sort order; more tells whether the fetch found rows
+// beyond the window in the direction of travel and previous whether the
+// window was reached by skipping rows in the opposite direction.
+func (c *cursorCodec) links(scope string, keys [][]*string, backward, more, previous bool) (next, prev string, err error) {
+	if len(keys) == 0 {
+		return "", "", nil
+	}
+
+	hasNext, hasPrev := more, previous
+	if backward {
+		hasNext, hasPrev = previous, more
+	}
+
+	if hasNext {
+		next, err = c.encode(cursor{Scope: scope, Values: keys[len(keys)-1]})
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if hasPrev {
+		prev, err = c.encode(cursor{Scope: scope, Values: keys[0], Prev: true})
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return next, prev, nil
+}
@@ -0,0 +1,231 @@
+package repo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+)
+
+func strptr(s string) *string {
+	return &s
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	codec, err := newCursorCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := cursorScope("movies", []string{"release_year desc"})
+
+	tests := []struct {
+		name string
+		cur  cursor
+		keys int
+	}{
+		{"forward", cursor{Scope: scope, Values: []*string{strptr("1999"), strptr("b1c2")}}, 2},
+		{"backward", cursor{Scope: scope, Values: []*string{strptr("1999"), strptr("b1c2")}, Prev: true}, 2},
+		{"null key", cursor{Scope: scope, Values: []*string{nil, strptr("b1c2")}}, 2},
+		{"window start with offset", cursor{Scope: scope, Offset: 20}, 2},
+		{"window offset", cursor{Scope: scope, Values: []*string{strptr("0.5"), strptr("b1c2")}, Offset: 10}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := codec.encode(tt.cur)
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+
+			got, err := codec.decode(token, scope, tt.keys)
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.cur) {
+				t.Errorf("decode() = %+v, want %+v", got, tt.cur)
+			}
+		})
+	}
+}
+
+func TestCursorDecodeRejects(t *testing.T) {
+	codec, err := newCursorCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newCursorCodec("other secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := cursorScope("movies")
+	valid, err := codec.encode(cursor{Scope: scope, Values: []*string{strptr("1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, _ := strings.Cut(valid, ".")
+	forged, err := other.encode(cursor{Scope: scope, Values: []*string{strptr("1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(cur cursor) string {
+		token, err := codec.encode(cur)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		scope string
+		keys  int
+		want  string
+	}{
+		{"garbage", "garbage", scope, 1, "invalid cursor"},
+		{"tampered payload", payload + "x." + signature, scope, 1, "invalid cursor"},
+		{"other secret", forged, scope, 1, "invalid cursor"},
+		{"wrong number of keys", valid, scope, 2, "invalid cursor"},
+		{"backward from the start", encode(cursor{Scope: scope, Prev: true}), scope, 1, "invalid cursor"},
+		{"negative offset", encode(cursor{Scope: scope, Offset: -1}), scope, 1, "invalid cursor"},
+		{"other query", valid, cursorScope("movies", "other"), 1, "different filters or sorting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := codec.decode(tt.token, tt.scope, tt.keys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("decode() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorLinks(t *testing.T) {
+	codec, err := newCursorCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := cursorScope("movies")
+	keys := [][]*string{{strptr("a")}, {strptr("b")}, {strptr("c")}}
+
+	tests := []struct {
+		name                     string
+		backward, more, previous bool
+		wantNext, wantPrev       *cursor
+	}{
+		{"first page", false, true, false, &cursor{Scope: scope, Values: keys[2]}, nil},
+		{"middle page", false, true, true, &cursor{Scope: scope, Values: keys[2]}, &cursor{Scope: scope, Values: keys[0], Prev: true}},
+		{"last page", false, false, true, nil, &cursor{Scope: scope, Values: keys[0], Prev: true}},
+		{"back to the first page", true, false, true, &cursor{Scope: scope, Values: keys[2]}, nil},
+		{"back to a middle page", true, true, true, &cursor{Scope: scope, Values: keys[2]}, &cursor{Scope: scope, Values: keys[0], Prev: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, prev, err := codec.links(scope, keys, tt.backward, tt.more, tt.previous)
+			if err != nil {
+				t.Fatalf("links() error = %v", err)
+			}
+
+			for _, link := range []struct {
+				token string
+				want  *cursor
+			}{{next, tt.wantNext}, {prev, tt.wantPrev}} {
+				if link.want == nil {
+					if link.token != "" {
+						t.Errorf("links() returned %q, want none", link.token)
+					}
+					continue
+				}
+
+				got, err := codec.decode(link.token, scope, 1)
+				if err != nil {
+					t.Fatalf("decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, *link.want) {
+					t.Errorf("links() cursor = %+v, want %+v", got, *link.want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	keys := []keysetKey{{expr: "release_year", desc: true}, {expr: "id"}}
+
+	tests := []struct {
+		name     string
+		values   []*string
+		backward bool
+		want     string
+		args     []interface{}
+	}{
+		{
+			name: "first page",
+			want: "TRUE",
+		},
+		{
+			name:   "forward",
+			values: []*string{strptr("1999"), strptr("m1")},
+			want:   "((release_year < ?) OR (release_year = ? AND (id > ? OR id IS NULL)))",
+			args:   []interface{}{"1999", "1999", "m1"},
+		},
+		{
+			name:     "backward",
+			values:   []*string{strptr("1999"), strptr("m1")},
+			backward: true,
+			want:     "(((release_year > ? OR release_year IS NULL)) OR (release_year = ? AND id < ?))",
+			args:     []interface{}{"1999", "1999", "m1"},
+		},
+		{
+			name:   "after a NULL sorted first",
+			values: []*string{nil, strptr("m1")},
+			want:   "((release_year IS NOT NULL) OR (release_year IS NULL AND (id > ? OR id IS NULL)))",
+			args:   []interface{}{"m1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := keysetCondition(keys, tt.values, tt.backward).ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if sql != tt.want {
+				t.Errorf("keysetCondition() = %s, want %s", sql, tt.want)
+			}
+			if len(args) != len(tt.args) || (len(args) > 0 && !reflect.DeepEqual(args, tt.args)) {
+				t.Errorf("keysetCondition() args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestKeysetOrder(t *testing.T) {
+	keys := []keysetKey{{expr: "release_year", desc: true}, {expr: "id"}}
+
+	if got, want := keysetOrder(keys, false), []string{"release_year DESC", "id ASC"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keysetOrder() = %v, want %v", got, want)
+	}
+	if got, want := keysetOrder(keys, true), []string{"release_year ASC", "id DESC"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keysetOrder(backward) = %v, want %v", got, want)
+	}
+}
+
+func TestNumberPlaceholders(t *testing.T) {
+	sql, _, err := squirrel.And{squirrel.Expr("a = ?", 1), squirrel.Expr("b IN (?, ?)", 2, 3)}.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := numberPlaceholders(sql, 4), "(a = $4 AND b IN ($5, $6))"; got != want {
+		t.Errorf("numberPlaceholders() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+// documentTemplate renders the text that represents a movie in the vector
+// space. Its hash is stored next to every embedding, so rows embedded with a
+// previous template can be found and embedded again.
+type documentTemplate struct {
+	tmpl *template.Template
+	hash string
+}
+
+func newDocumentTemplate(text string) (*documentTemplate, error) {
+	tmpl, err := template.New("embedding").
+		Funcs(template.FuncMap{
+			"join":  strings.Join,
+			"lower": strings.ToLower,
+			"upper": strings.ToUpper,
+			"trim":  strings.TrimSpace,
+		}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse embedding template: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(text))
+
+	return &documentTemplate{
+		tmpl: tmpl,
+		hash: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// Render executes the template over movie and drops blank lines from the output.
+func (d *documentTemplate) Render(movie *entity.Movie) (string, error) {
+	var builder strings.Builder
+
+	if err := d.tmpl.Execute(&builder, movie); err != nil {
+		return "", fmt.Errorf("execute embedding template: %w", err)
+	}
+
+	lines := strings.Split(builder.String(), "\n")
+	parts := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	return strings.Join(parts, "\n"), nil
+}
+
+// Hash -.
+func (d *documentTemplate) Hash() string {
+	return d.hash
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// embeddingDimensions is the size of the movies.embedding column.
+const embeddingDimensions = 768
+
+// fixedModelDimensions lists the output size of models that cannot shorten
+// their embeddings.
+var fixedModelDimensions = map[string]int{
+	"text-embedding-ada-002": 1536,
+}
+
+// checkModelDimensions fails with a bad request when model cannot return
+// embeddings of the given size. Sizes of unknown models are not checked.
+func checkModelDimensions(model string, dimensions int) error {
+	if strings.HasPrefix(model, "text-embedding-3") {
+		return nil
+	}
+
+	if size, ok := fixedModelDimensions[model]; ok && size != dimensions {
+		return fmt.Errorf(config.ErrorBadRequest+"the embedding model %s only returns %d dimensions", model, size)
+	}
+
+	return nil
+}
+
+// createEmbeddings embeds every input with a single provider call and returns
+// the vectors in input order. A positive dimensions is forwarded to models that
+// support shortening their output; the result is checked against it either way.
+func createEmbeddings(ctx context.Context, client *openai.Client, model string, inputs []string, dimensions int) ([][]float32, error) {
+	req := openai.EmbeddingRequest{
+		Input: inputs,
+		Model: openai.EmbeddingModel(model),
+	}
+	if dimensions > 0 && strings.HasPrefix(model, "text-embedding-3") {
+		req.Dimensions = dimensions
+	}
+
+	resp, err := client.CreateEmbeddings(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEmbeddings: %w", err)
+	}
+
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("CreateEmbeddings: got %d embeddings for %d inputs", len(resp.Data), len(inputs))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) {
+			return nil, fmt.Errorf("CreateEmbeddings: embedding index %d out of range", item.Index)
+		}
+		if dimensions > 0 && len(item.Embedding) != dimensions {
+			return nil, fmt.Errorf("CreateEmbeddings: model %s returned %d dimensions, expected %d", model, len(item.Embedding), dimensions)
+		}
+
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	TotalCost    float64    `json:"Total Cost"`
+	PlanRows     float64    `json:"Plan Rows"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// explainPlan runs EXPLAIN for the given query and returns a one-line summary
+// of the plan tree together with its estimated total cost.
+func (r *MovieRepo) explainPlan(ctx context.Context, query string, args ...interface{}) (string, float64, error) {
+	var raw []byte
+
+	err := r.pg.Pool.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw)
+	if err != nil {
+		return "", 0, fmt.Errorf("MovieRepo - explainPlan - QueryRow: %w", err)
+	}
+
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err = json.Unmarshal(raw, &plans); err != nil {
+		return "", 0, fmt.Errorf("MovieRepo - explainPlan - Unmarshal: %w", err)
+	}
+
+	if len(plans) == 0 {
+		return "", 0, nil
+	}
+
+	var steps []string
+	summarizePlan(plans[0].Plan, &steps)
+
+	return strings.Join(steps, " -> "), plans[0].Plan.TotalCost, nil
+}
+
+func summarizePlan(node planNode, steps *[]string) {
+	step := node.NodeType
+	switch {
+	case node.IndexName != "":
+		step += " using " + node.IndexName
+	case node.RelationName != "":
+		step += " on " + node.RelationName
+	}
+	*steps = append(*steps, fmt.Sprintf("%s (rows=%.0f)", step, node.PlanRows))
+
+	for _, child := range node.Plans {
+		summarizePlan(child, steps)
+	}
+}
@@ -0,0 +1,308 @@
+package repo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Value types of registry fields. Collection fields use the same names.
+const (
+	fieldText      = "text"
+	fieldInteger   = "integer"
+	fieldNumber    = "number"
+	fieldBoolean   = "boolean"
+	fieldTimestamp = "timestamp"
+	fieldUUID      = "uuid"
+	// fieldTextList is a TEXT[] column; filters match its elements.
+	fieldTextList = "text[]"
+)
+
+// field describes a column list queries may filter or sort on.
+type field struct {
+	column     string // SQL expression; the public name is used when empty
+	typ        string
+	filterable bool
+	sortable   bool
+}
+
+// fieldRegistry maps the public names of the fields of an entity to their
+// descriptions. Filters and sort orders naming anything else are rejected,
+// so no user input reaches SQL as an identifier.
+type fieldRegistry map[string]field
+
+func (f field) sql(name string) string {
+	if f.column != "" {
+		return f.column
+	}
+
+	return name
+}
+
+// parse converts a filter value to the type of the field.
+func (f field) parse(name, value string) (interface{}, error) {
+	var (
+		parsed interface{}
+		err    error
+	)
+
+	switch f.typ {
+	case fieldInteger:
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case fieldNumber:
+		parsed, err = strconv.ParseFloat(value, 64)
+	case fieldBoolean:
+		parsed, err = strconv.ParseBool(value)
+	case fieldTimestamp:
+		parsed, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", value)
+		}
+	case fieldUUID:
+		var id uuid.UUID
+		id, err = uuid.Parse(value)
+		parsed = id.String()
+	default:
+		parsed = value
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(config.ErrorBadRequest+"invalid %s value %q for %s", f.typ, value, name)
+	}
+
+	return parsed, nil
+}
+
+func (f field) parseList(name, value string) ([]interface{}, error) {
+	var values []interface{}
+
+	for _, item := range strings.Split(value, ",") {
+		parsed, err := f.parse(name, strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+
+	return values, nil
+}
+
+var (
+	movieFields = fieldRegistry{
+		"id":               {typ: fieldUUID, filterable: true},
+		"slug":             {typ: fieldText, filterable: true, sortable: true},
+		"name_uz":          {typ: fieldText, filterable: true, sortable: true},
+		"name_en":          {typ: fieldText, filterable: true, sortable: true},
+		"name_ru":          {typ: fieldText, filterable: true, sortable: true},
+		"aliases":          {typ: fieldTextList, filterable: true},
+		"description_uz":   {typ: fieldText, filterable: true},
+		"description_en":   {typ: fieldText, filterable: true},
+		"description_ru":   {typ: fieldText, filterable: true},
+		"release_year":     {typ: fieldInteger, filterable: true, sortable: true},
+		"runtime_minutes":  {typ: fieldInteger, filterable: true, sortable: true},
+		"country":          {typ: fieldText, filterable: true, sortable: true},
+		"poster_url":       {typ: fieldText, filterable: true},
+		"embedding_status": {typ: fieldText, filterable: true, sortable: true},
+		"external_source":  {typ: fieldText, filterable: true, sortable: true},
+		"external_id":      {typ: fieldText, filterable: true, sortable: true},
+		"version":          {typ: fieldInteger, filterable: true, sortable: true},
+		"created_at":       {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at":       {typ: fieldTimestamp, filterable: true, sortable: true},
+		"deleted_at":       {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	genreFields = fieldRegistry{
+		"id":         {typ: fieldUUID, filterable: true},
+		"slug":       {typ: fieldText, filterable: true, sortable: true},
+		"name_uz":    {typ: fieldText, filterable: true, sortable: true},
+		"name_en":    {typ: fieldText, filterable: true, sortable: true},
+		"name_ru":    {typ: fieldText, filterable: true, sortable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	personFields = fieldRegistry{
+		"id":         {typ: fieldUUID, filterable: true},
+		"full_name":  {typ: fieldText, filterable: true, sortable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	synonymFields = fieldRegistry{
+		"id":         {typ: fieldUUID, filterable: true},
+		"term":       {typ: fieldText, filterable: true, sortable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	tenantFields = fieldRegistry{
+		"id":         {typ: fieldUUID, filterable: true},
+		"name":       {typ: fieldText, filterable: true, sortable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	jobFields = fieldRegistry{
+		"id":          {typ: fieldUUID, filterable: true},
+		"kind":        {typ: fieldText, filterable: true, sortable: true},
+		"state":       {typ: fieldText, filterable: true, sortable: true},
+		"attempts":    {typ: fieldInteger, filterable: true, sortable: true},
+		"actor":       {typ: fieldText, filterable: true, sortable: true},
+		"run_at":      {typ: fieldTimestamp, filterable: true, sortable: true},
+		"finished_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"created_at":  {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at":  {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	collectionFields = fieldRegistry{
+		"name":       {typ: fieldText, filterable: true, sortable: true},
+		"dimension":  {typ: fieldInteger, filterable: true, sortable: true},
+		"metric":     {typ: fieldText, filterable: true, sortable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+)
+
+// documentFields builds the registry of a collection from its declared
+// fields. Columns are quoted since field names may collide with SQL keywords.
+func documentFields(coll entity.Collection) fieldRegistry {
+	fields := fieldRegistry{
+		"id":         {typ: fieldUUID, filterable: true},
+		"created_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+		"updated_at": {typ: fieldTimestamp, filterable: true, sortable: true},
+	}
+
+	for _, f := range coll.Fields {
+		fields[f.Name] = field{
+			column:     pgx.Identifier{f.Name}.Sanitize(),
+			typ:        f.Type,
+			filterable: true,
+			sortable:   true,
+		}
+	}
+
+	return fields
+}
+
+// filterCondition translates one filter into a condition on a registry field.
+func filterCondition(fields fieldRegistry, filter entity.Filter) (squirrel.Sqlizer, error) {
+	f, ok := fields[filter.Column]
+	if !ok || !f.filterable {
+		return nil, fmt.Errorf(config.ErrorBadRequest+"cannot filter by %q", filter.Column)
+	}
+
+	column := f.sql(filter.Column)
+
+	if f.typ == fieldTextList {
+		return listFilterCondition(column, filter)
+	}
+
+	switch filter.Type {
+	case "eq", "neq", "gt", "gte", "lt", "lte":
+		value, err := f.parse(filter.Column, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch filter.Type {
+		case "eq":
+			return squirrel.Eq{column: value}, nil
+		case "neq":
+			return squirrel.NotEq{column: value}, nil
+		case "gt":
+			return squirrel.Gt{column: value}, nil
+		case "gte":
+			return squirrel.GtOrEq{column: value}, nil
+		case "lt":
+			return squirrel.Lt{column: value}, nil
+		default:
+			return squirrel.LtOrEq{column: value}, nil
+		}
+	case "search":
+		if f.typ != fieldText {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"cannot search in %s field %q", f.typ, filter.Column)
+		}
+		return squirrel.ILike{column: "%" + escapeLike(filter.Value) + "%"}, nil
+	case "in", "not_in":
+		values, err := f.parseList(filter.Column, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Type == "in" {
+			return squirrel.Eq{column: values}, nil
+		}
+		return squirrel.NotEq{column: values}, nil
+	case "is_null":
+		isNull := true
+		if filter.Value != "" {
+			var err error
+			if isNull, err = strconv.ParseBool(filter.Value); err != nil {
+				return nil, fmt.Errorf(config.ErrorBadRequest+"is_null expects true or false, got %q", filter.Value)
+			}
+		}
+
+		if isNull {
+			return squirrel.Eq{column: nil}, nil
+		}
+		return squirrel.NotEq{column: nil}, nil
+	case "between":
+		values, err := f.parseList(filter.Column, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"between expects two comma-separated values for %s", filter.Column)
+		}
+
+		return squirrel.Expr(column+" BETWEEN ? AND ?", values[0], values[1]), nil
+	default:
+		return nil, fmt.Errorf(config.ErrorBadRequest+"unknown filter type %q", filter.Type)
+	}
+}
+
+// listFilterCondition translates a filter on a TEXT[] column: eq and neq test
+// for an element, in and not_in for any of the listed elements, search looks
+// into all elements and is_null matches empty lists.
+func listFilterCondition(column string, filter entity.Filter) (squirrel.Sqlizer, error) {
+	switch filter.Type {
+	case "eq":
+		return squirrel.Expr("? = ANY("+column+")", filter.Value), nil
+	case "neq":
+		return squirrel.Expr("NOT (? = ANY("+column+"))", filter.Value), nil
+	case "in", "not_in":
+		var values []string
+		for _, item := range strings.Split(filter.Value, ",") {
+			values = append(values, strings.TrimSpace(item))
+		}
+
+		if filter.Type == "in" {
+			return squirrel.Expr(column+" && ?", values), nil
+		}
+		return squirrel.Expr("NOT ("+column+" && ?)", values), nil
+	case "search":
+		return squirrel.Expr("array_to_string("+column+", ' ') ILIKE ?", "%"+escapeLike(filter.Value)+"%"), nil
+	case "is_null":
+		isNull := true
+		if filter.Value != "" {
+			var err error
+			if isNull, err = strconv.ParseBool(filter.Value); err != nil {
+				return nil, fmt.Errorf(config.ErrorBadRequest+"is_null expects true or false, got %q", filter.Value)
+			}
+		}
+
+		if isNull {
+			return squirrel.Expr("COALESCE(cardinality(" + column + "), 0) = 0"), nil
+		}
+		return squirrel.Expr("cardinality(" + column + ") > 0"), nil
+	default:
+		return nil, fmt.Errorf(config.ErrorBadRequest+"cannot filter list field %q with %q", filter.Column, filter.Type)
+	}
+}
@@ -0,0 +1,293 @@
+package repo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+var testFields = fieldRegistry{
+	"id":           {typ: fieldUUID, filterable: true},
+	"name":         {typ: fieldText, filterable: true, sortable: true},
+	"year":         {column: "release_year", typ: fieldInteger, filterable: true, sortable: true},
+	"rating":       {typ: fieldNumber, filterable: true},
+	"adult":        {typ: fieldBoolean, filterable: true},
+	"created_at":   {typ: fieldTimestamp, filterable: true, sortable: true},
+	"aliases":      {typ: fieldTextList, filterable: true},
+	"api_key_hash": {typ: fieldText},
+}
+
+func TestFilterCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  entity.Filter
+		want    string
+		args    []interface{}
+		wantErr string
+	}{
+		{
+			name:   "eq on a renamed column",
+			filter: entity.Filter{Column: "year", Type: "eq", Value: "1999"},
+			want:   "release_year = ?",
+			args:   []interface{}{int64(1999)},
+		},
+		{
+			name:   "neq",
+			filter: entity.Filter{Column: "name", Type: "neq", Value: "Heat"},
+			want:   "name <> ?",
+			args:   []interface{}{"Heat"},
+		},
+		{
+			name:   "gte on a number",
+			filter: entity.Filter{Column: "rating", Type: "gte", Value: "7.5"},
+			want:   "rating >= ?",
+			args:   []interface{}{7.5},
+		},
+		{
+			name:   "lt on a date",
+			filter: entity.Filter{Column: "created_at", Type: "lt", Value: "2024-01-02"},
+			want:   "created_at < ?",
+			args:   []interface{}{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "search escapes wildcards",
+			filter: entity.Filter{Column: "name", Type: "search", Value: "100%_off"},
+			want:   "name ILIKE ?",
+			args:   []interface{}{`%100\%\_off%`},
+		},
+		{
+			name:   "in",
+			filter: entity.Filter{Column: "year", Type: "in", Value: "1999, 2003"},
+			want:   "release_year IN (?,?)",
+			args:   []interface{}{int64(1999), int64(2003)},
+		},
+		{
+			name:   "not_in",
+			filter: entity.Filter{Column: "name", Type: "not_in", Value: "a,b"},
+			want:   "name NOT IN (?,?)",
+			args:   []interface{}{"a", "b"},
+		},
+		{
+			name:   "between",
+			filter: entity.Filter{Column: "year", Type: "between", Value: "1990,1999"},
+			want:   "release_year BETWEEN ? AND ?",
+			args:   []interface{}{int64(1990), int64(1999)},
+		},
+		{
+			name:   "is_null without a value",
+			filter: entity.Filter{Column: "name", Type: "is_null"},
+			want:   "name IS NULL",
+		},
+		{
+			name:   "is_null false",
+			filter: entity.Filter{Column: "name", Type: "is_null", Value: "false"},
+			want:   "name IS NOT NULL",
+		},
+		{
+			name:   "eq on a list",
+			filter: entity.Filter{Column: "aliases", Type: "eq", Value: "Alpha"},
+			want:   "? = ANY(aliases)",
+			args:   []interface{}{"Alpha"},
+		},
+		{
+			name:   "not_in on a list",
+			filter: entity.Filter{Column: "aliases", Type: "not_in", Value: "a, b"},
+			want:   "NOT (aliases && ?)",
+			args:   []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:   "search in a list",
+			filter: entity.Filter{Column: "aliases", Type: "search", Value: "alp"},
+			want:   "array_to_string(aliases, ' ') ILIKE ?",
+			args:   []interface{}{"%alp%"},
+		},
+		{
+			name:   "is_null on a list",
+			filter: entity.Filter{Column: "aliases", Type: "is_null", Value: "true"},
+			want:   "COALESCE(cardinality(aliases), 0) = 0",
+		},
+		{
+			name:    "unknown column",
+			filter:  entity.Filter{Column: "name; DROP TABLE movies", Type: "eq", Value: "x"},
+			wantErr: "cannot filter by",
+		},
+		{
+			name:    "column that is not filterable",
+			filter:  entity.Filter{Column: "api_key_hash", Type: "eq", Value: "x"},
+			wantErr: "cannot filter by",
+		},
+		{
+			name:    "unknown operator",
+			filter:  entity.Filter{Column: "name", Type: "like", Value: "x"},
+			wantErr: "unknown filter type",
+		},
+		{
+			name:    "missing operator",
+			filter:  entity.Filter{Column: "name", Value: "x"},
+			wantErr: "unknown filter type",
+		},
+		{
+			name:    "unknown operator on a list",
+			filter:  entity.Filter{Column: "aliases", Type: "gt", Value: "x"},
+			wantErr: "cannot filter list field",
+		},
+		{
+			name:    "between with one value",
+			filter:  entity.Filter{Column: "year", Type: "between", Value: "1990"},
+			wantErr: "between expects two",
+		},
+		{
+			name:    "between with three values",
+			filter:  entity.Filter{Column: "year", Type: "between", Value: "1990,1995,1999"},
+			wantErr: "between expects two",
+		},
+		{
+			name:    "is_null with a value that is no boolean",
+			filter:  entity.Filter{Column: "name", Type: "is_null", Value: "maybe"},
+			wantErr: "is_null expects true or false",
+		},
+		{
+			name:    "integer that does not parse",
+			filter:  entity.Filter{Column: "year", Type: "gt", Value: "1999a"},
+			wantErr: "invalid integer value",
+		},
+		{
+			name:    "list with a value that does not parse",
+			filter:  entity.Filter{Column: "year", Type: "in", Value: "1999,x"},
+			wantErr: "invalid integer value",
+		},
+		{
+			name:    "uuid that does not parse",
+			filter:  entity.Filter{Column: "id", Type: "eq", Value: "1 OR 1=1"},
+			wantErr: "invalid uuid value",
+		},
+		{
+			name:    "boolean that does not parse",
+			filter:  entity.Filter{Column: "adult", Type: "eq", Value: "yes please"},
+			wantErr: "invalid boolean value",
+		},
+		{
+			name:    "timestamp that does not parse",
+			filter:  entity.Filter{Column: "created_at", Type: "gte", Value: "yesterday"},
+			wantErr: "invalid timestamp value",
+		},
+		{
+			name:    "search in a number",
+			filter:  entity.Filter{Column: "year", Type: "search", Value: "19"},
+			wantErr: "cannot search in integer field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := filterCondition(testFields, tt.filter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("filterCondition() error = %v, want %q", err, tt.wantErr)
+				}
+				if !strings.HasPrefix(err.Error(), config.ErrorBadRequest) {
+					t.Errorf("filterCondition() error = %v, want a bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filterCondition() error = %v", err)
+			}
+
+			sql, args, err := condition.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if sql != tt.want {
+				t.Errorf("filterCondition() = %s, want %s", sql, tt.want)
+			}
+			if len(args) != len(tt.args) || (len(args) > 0 && !reflect.DeepEqual(args, tt.args)) {
+				t.Errorf("filterCondition() args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestPrepareGetListQuery(t *testing.T) {
+	base := squirrel.StatementBuilder.Select("id").From("movies")
+
+	tests := []struct {
+		name    string
+		req     entity.GetListFilter
+		want    string
+		args    []interface{}
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: "SELECT id FROM movies WHERE (1=1) LIMIT 10 OFFSET 0",
+		},
+		{
+			name: "searches are combined with OR",
+			req: entity.GetListFilter{
+				Page:  3,
+				Limit: 20,
+				Filters: []entity.Filter{
+					{Column: "name", Type: "search", Value: "star"},
+					{Column: "year", Type: "gte", Value: "1990"},
+					{Column: "aliases", Type: "search", Value: "wars"},
+				},
+				OrderBy: []entity.OrderBy{{Column: "year", Order: "DESC"}, {Column: "name"}},
+			},
+			want: "SELECT id FROM movies WHERE (release_year >= ? AND (name ILIKE ? OR array_to_string(aliases, ' ') ILIKE ?)) " +
+				"ORDER BY release_year DESC, name ASC LIMIT 20 OFFSET 40",
+			args: []interface{}{int64(1990), "%star%", "%wars%"},
+		},
+		{
+			name:    "bad filter",
+			req:     entity.GetListFilter{Filters: []entity.Filter{{Column: "password", Type: "eq", Value: "x"}}},
+			wantErr: "cannot filter by",
+		},
+		{
+			name:    "unknown sort column",
+			req:     entity.GetListFilter{OrderBy: []entity.OrderBy{{Column: "name, (SELECT 1)"}}},
+			wantErr: "cannot sort by",
+		},
+		{
+			name:    "column that is not sortable",
+			req:     entity.GetListFilter{OrderBy: []entity.OrderBy{{Column: "aliases"}}},
+			wantErr: "cannot sort by",
+		},
+		{
+			name:    "unknown sort order",
+			req:     entity.GetListFilter{OrderBy: []entity.OrderBy{{Column: "name", Order: "sideways"}}},
+			wantErr: "invalid sort order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _, err := PrepareGetListQuery(base, testFields, tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PrepareGetListQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareGetListQuery() error = %v", err)
+			}
+
+			sql, args, err := query.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if sql != tt.want {
+				t.Errorf("PrepareGetListQuery() = %s, want %s", sql, tt.want)
+			}
+			if len(args) != len(tt.args) || (len(args) > 0 && !reflect.DeepEqual(args, tt.args)) {
+				t.Errorf("PrepareGetListQuery() args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
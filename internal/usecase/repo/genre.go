@@ -0,0 +1,260 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type GenreRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewGenreRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *GenreRepo {
+	return &GenreRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *GenreRepo) Create(ctx context.Context, req entity.Genre) (entity.Genre, error) {
+	req.ID = uuid.NewString()
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("genres").
+		Columns(`id, tenant_id, slug, name_uz, name_en, name_ru`).
+		Values(req.ID, scope["tenant_id"], req.Slug, req.NameUz, req.NameEn, req.NameRu).
+		Suffix("RETURNING created_at, updated_at").ToSql()
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return req, nil
+}
+
+func (r *GenreRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Genre, error) {
+	var (
+		response             = entity.Genre{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, slug, name_uz, name_en, name_ru, created_at, updated_at`).
+		From("genres").
+		Where(scope)
+
+	switch {
+	case req.ID != "":
+		qeuryBuilder = qeuryBuilder.Where("id = ?", req.ID)
+	case req.Slug != "":
+		qeuryBuilder = qeuryBuilder.Where("slug = ?", req.Slug)
+	default:
+		return response, pgx.ErrNoRows
+	}
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.Slug, &response.NameUz, &response.NameEn, &response.NameRu, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *GenreRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.GenreList, error) {
+	var (
+		response             = entity.GenreList{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, slug, name_uz, name_en, name_ru, created_at, updated_at`).
+		From("genres").
+		Where(scope)
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, genreFields, req)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, scope)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Genre
+		err = rows.Scan(&item.ID, &item.Slug, &item.NameUz, &item.NameEn, &item.NameRu, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("genres").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *GenreRepo) Update(ctx context.Context, req entity.Genre) (entity.Genre, error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	mp := map[string]interface{}{
+		"slug":       strings.ToLower(strings.TrimSpace(req.Slug)),
+		"name_uz":    req.NameUz,
+		"name_en":    req.NameEn,
+		"name_ru":    req.NameRu,
+		"updated_at": "now()",
+	}
+
+	qeury, args, err := r.pg.Builder.Update("genres").SetMap(mp).Where("id = ?", req.ID).Where(scope).ToSql()
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	n, err := tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	if n.RowsAffected() == 0 {
+		return entity.Genre{}, pgx.ErrNoRows
+	}
+
+	// The embedding documents of the linked movies show its names.
+	ids, err := bumpLinkedMovies(ctx, tx, "movie_genres", "genre_id", req.ID)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	if err = queueEmbeddings(ctx, tx, ids); err != nil {
+		return entity.Genre{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Genre{}, err
+	}
+
+	return r.GetSingle(ctx, entity.Id{ID: req.ID})
+}
+
+// Delete removes the genre from the movies it is linked to and deletes it.
+// Those movies get a new version and are queued for the embedding workers,
+// since their documents change.
+func (r *GenreRepo) Delete(ctx context.Context, req entity.Id) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	qeury, args, err := r.pg.Builder.Select("id").From("genres").Where("id = ?", req.ID).Where(scope).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ids, err := bumpLinkedMovies(ctx, tx, "movie_genres", "genre_id", id)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM movie_genres WHERE genre_id = $1", id); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM genres WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	if err = queueEmbeddings(ctx, tx, ids); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
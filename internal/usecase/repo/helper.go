@@ -1,30 +1,56 @@
 package repo
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
 	"github.com/abdulazizax/ai-embedding/internal/entity"
 )
 
-func PrepareFilter(filters []entity.Filter) squirrel.And {
+// suggestColumns maps the lang parameter of typeahead requests to the title column it reads.
+var suggestColumns = map[string]string{
+	"uz": "name_uz",
+	"en": "name_en",
+	"ru": "name_ru",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// sortOrder validates a sort direction and returns it normalized.
+func sortOrder(order string) (string, error) {
+	switch strings.ToLower(order) {
+	case "", "asc":
+		return "asc", nil
+	case "desc":
+		return "desc", nil
+	}
+
+	return "", fmt.Errorf(config.ErrorBadRequest+"invalid sort order %q", order)
+}
+
+// PrepareFilter translates filters on the fields of a registry into
+// conditions. All search filters are combined with OR, everything else with AND.
+func PrepareFilter(fields fieldRegistry, filters []entity.Filter) (squirrel.And, error) {
 	where := squirrel.And{}
 	or := squirrel.Or{}
 
 	for _, e := range filters {
-		switch e.Type {
-		case "eq":
-			where = append(where, squirrel.Eq{e.Column: e.Value})
-		case "neq":
-			where = append(where, squirrel.NotEq{e.Column: e.Value})
-		case "gt":
-			where = append(where, squirrel.Gt{e.Column: e.Value})
-		case "gte":
-			where = append(where, squirrel.GtOrEq{e.Column: e.Value})
-		case "lt":
-			where = append(where, squirrel.Lt{e.Column: e.Value})
-		case "lte":
-			where = append(where, squirrel.LtOrEq{e.Column: e.Value})
-		case "search":
-			or = append(or, squirrel.ILike{e.Column: "%" + e.Value + "%"})
+		condition, err := filterCondition(fields, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Type == "search" {
+			or = append(or, condition)
+		} else {
+			where = append(where, condition)
 		}
 	}
 
@@ -32,17 +58,22 @@ func PrepareFilter(filters []entity.Filter) squirrel.And {
 		where = append(where, or)
 	}
 
-	return where
+	return where, nil
 }
 
-func PrepareGetListQuery(selectQuery squirrel.SelectBuilder, filterRequest entity.GetListFilter) (query squirrel.SelectBuilder, where squirrel.And) {
-	where = PrepareFilter(filterRequest.Filters)
+func PrepareGetListQuery(selectQuery squirrel.SelectBuilder, fields fieldRegistry, filterRequest entity.GetListFilter) (query squirrel.SelectBuilder, where squirrel.And, err error) {
+	where, err = PrepareFilter(fields, filterRequest.Filters)
+	if err != nil {
+		return selectQuery, nil, err
+	}
 
 	selectQuery = selectQuery.Where(where)
 
-	for _, e := range filterRequest.OrderBy {
-		selectQuery = selectQuery.OrderBy(e.Column + " " + e.Order)
+	keys, err := sortKeys(fields, filterRequest.OrderBy)
+	if err != nil {
+		return selectQuery, nil, err
 	}
+	selectQuery = selectQuery.OrderBy(keysetOrder(keys, false)...)
 
 	if filterRequest.Limit <= 0 {
 		filterRequest.Limit = 10
@@ -54,5 +85,5 @@ func PrepareGetListQuery(selectQuery squirrel.SelectBuilder, filterRequest entit
 
 	selectQuery = selectQuery.Limit(uint64(filterRequest.Limit)).Offset(uint64((filterRequest.Page - 1) * filterRequest.Limit))
 
-	return selectQuery, where
+	return selectQuery, where, nil
 }
@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/jackc/pgx/v4"
+)
+
+// idempotencyPurgeBatch is the number of keys PurgeExpired deletes per statement.
+const idempotencyPurgeBatch = 1000
+
+type IdempotencyRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewIdempotencyRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Claim reserves key for a request with the given hash for the duration of
+// lock, unless the tenant already used it and it has not expired. It reports
+// whether the key was claimed; when it was not, the stored request hash and,
+// once the first request finished, its response are returned.
+func (r *IdempotencyRepo) Claim(ctx context.Context, key, requestHash string, lock time.Duration) (entity.IdempotentResponse, bool, error) {
+	response := entity.IdempotentResponse{Key: key}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, false, err
+	}
+
+	// The key may expire between both statements; the second attempt claims it then.
+	for attempt := 0; attempt < 2; attempt++ {
+		var claimed bool
+
+		err = r.pg.Pool.QueryRow(ctx, `INSERT INTO idempotency_keys (tenant_id, key, request_hash, expires_at)
+			VALUES ($1, $2, $3, now() + $4::interval)
+			ON CONFLICT (tenant_id, key) DO UPDATE SET request_hash = EXCLUDED.request_hash,
+				status = NULL, header = NULL, body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < now()
+			RETURNING true`, scope["tenant_id"], key, requestHash, lock).Scan(&claimed)
+		if err == nil {
+			response.RequestHash = requestHash
+			return response, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return response, false, err
+		}
+
+		var (
+			status *int
+			header []byte
+		)
+
+		err = r.pg.Pool.QueryRow(ctx, `SELECT request_hash, status, header, body FROM idempotency_keys
+			WHERE tenant_id = $1 AND key = $2 AND expires_at >= now()`, scope["tenant_id"], key).
+			Scan(&response.RequestHash, &status, &header, &response.Body)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return response, false, err
+		}
+
+		if status != nil {
+			response.Status = *status
+		}
+		if len(header) > 0 {
+			if err = json.Unmarshal(header, &response.Header); err != nil {
+				return response, false, err
+			}
+		}
+
+		return response, false, nil
+	}
+
+	return response, false, err
+}
+
+// Complete stores the response to the request that claimed req.Key, to be
+// replayed for ttl.
+func (r *IdempotencyRepo) Complete(ctx context.Context, req entity.IdempotentResponse, ttl time.Duration) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(req.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, `UPDATE idempotency_keys SET status = $1, header = $2, body = $3, expires_at = now() + $4::interval
+		WHERE tenant_id = $5 AND key = $6 AND request_hash = $7 AND status IS NULL`,
+		req.Status, header, req.Body, ttl, scope["tenant_id"], req.Key, req.RequestHash)
+
+	return err
+}
+
+// Release frees a key claimed by a request whose response is not stored, so
+// a retry runs the request again.
+func (r *IdempotencyRepo) Release(ctx context.Context, key, requestHash string) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND key = $2 AND request_hash = $3 AND status IS NULL`,
+		scope["tenant_id"], key, requestHash)
+
+	return err
+}
+
+// PurgeExpired deletes the expired keys of all tenants and returns their number.
+func (r *IdempotencyRepo) PurgeExpired(ctx context.Context) (int, error) {
+	var total int
+
+	for {
+		n, err := r.pg.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE (tenant_id, key) IN (
+			SELECT tenant_id, key FROM idempotency_keys WHERE expires_at < now() LIMIT $1
+		)`, idempotencyPurgeBatch)
+		if err != nil {
+			return total, err
+		}
+
+		total += int(n.RowsAffected())
+		if n.RowsAffected() < idempotencyPurgeBatch {
+			return total, nil
+		}
+	}
+}
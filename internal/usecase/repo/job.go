@@ -0,0 +1,369 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/audit"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// jobColumns are the columns scanJob reads, in order. The input is left out
+// since only the worker running the job needs it.
+const jobColumns = `id, tenant_id, kind, state, payload, result, error, progress_done, progress_total,
+	attempts, max_attempts, cancel_requested, actor, run_at, started_at, finished_at, created_at, updated_at`
+
+type JobRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewJobRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *JobRepo {
+	return &JobRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func scanJob(row pgx.Row, job *entity.Job, extra ...interface{}) error {
+	var (
+		tenantID                    *string
+		payload, result             []byte
+		runAt, createdAt, updatedAt time.Time
+		startedAt, finishedAt       *time.Time
+	)
+
+	dest := append([]interface{}{
+		&job.ID, &tenantID, &job.Kind, &job.State, &payload, &result, &job.Error,
+		&job.Progress.Done, &job.Progress.Total, &job.Attempts, &job.MaxAttempts, &job.CancelRequested,
+		&job.Actor, &runAt, &startedAt, &finishedAt, &createdAt, &updatedAt,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	if tenantID != nil {
+		job.TenantID = *tenantID
+	}
+	job.Payload = payload
+	job.Result = result
+	job.RunAt = runAt.Format(time.RFC3339)
+	job.CreatedAt = createdAt.Format(time.RFC3339)
+	job.UpdatedAt = updatedAt.Format(time.RFC3339)
+	if startedAt != nil {
+		job.StartedAt = startedAt.Format(time.RFC3339)
+	}
+	if finishedAt != nil {
+		job.FinishedAt = finishedAt.Format(time.RFC3339)
+	}
+
+	return nil
+}
+
+// nullable maps empty strings to NULL.
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// enqueueAttempts bounds how often Enqueue inserts again when the job holding
+// the unique key cannot be read, e.g. because row-level security hides it.
+const enqueueAttempts = 10
+
+// Enqueue queues a job for the tenant and actor of ctx; without a tenant it
+// becomes a system job. When a job with the same unique key is already
+// queued or running, that job is returned instead.
+func (r *JobRepo) Enqueue(ctx context.Context, req entity.Job) (entity.Job, error) {
+	if len(req.Payload) == 0 {
+		req.Payload = json.RawMessage("{}")
+	}
+
+	if req.MaxAttempts <= 0 {
+		req.MaxAttempts = r.config.Jobs.MaxAttempts
+	}
+	if req.MaxAttempts <= 0 {
+		req.MaxAttempts = 1
+	}
+
+	if max := r.config.Jobs.MaxInput; max > 0 && len(req.Input) > max {
+		return entity.Job{}, fmt.Errorf(config.ErrorBadRequest+"job input may be at most %d bytes", max)
+	}
+
+	qeury := `INSERT INTO jobs (id, tenant_id, kind, payload, input, max_attempts, unique_key, actor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (unique_key) WHERE state IN ('queued', 'running') DO NOTHING
+		RETURNING ` + jobColumns
+
+	// The job holding the unique key may finish between the insert and
+	// reading it, which frees the key for another insert.
+	for attempt := 1; ; attempt++ {
+		var response entity.Job
+
+		err := scanJob(r.pg.Pool.QueryRow(ctx, qeury,
+			uuid.NewString(), nullable(tenant.ID(ctx)), req.Kind, []byte(req.Payload), req.Input,
+			req.MaxAttempts, nullable(req.UniqueKey), audit.Actor(ctx)), &response)
+		if errors.Is(err, pgx.ErrNoRows) && req.UniqueKey != "" {
+			response, err = r.active(ctx, req.UniqueKey)
+			if errors.Is(err, pgx.ErrNoRows) && attempt < enqueueAttempts {
+				continue
+			}
+		}
+		if err != nil {
+			return entity.Job{}, err
+		}
+
+		return response, nil
+	}
+}
+
+// active returns the queued or running job with the unique key.
+func (r *JobRepo) active(ctx context.Context, uniqueKey string) (entity.Job, error) {
+	var response entity.Job
+
+	qeury, args, err := r.pg.Builder.
+		Select(jobColumns).
+		From("jobs").
+		Where("unique_key = ?", uniqueKey).
+		Where("state IN ('queued', 'running')").ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = scanJob(r.pg.Pool.QueryRow(ctx, qeury, args...), &response)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	return response, nil
+}
+
+func (r *JobRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Job, error) {
+	var response entity.Job
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(jobColumns).
+		From("jobs").
+		Where("id = ?", req.ID).
+		Where(scope).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = scanJob(r.pg.Pool.QueryRow(ctx, qeury, args...), &response)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	return response, nil
+}
+
+func (r *JobRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.JobList, error) {
+	var response = entity.JobList{}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(jobColumns).
+		From("jobs").
+		Where(scope)
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, jobFields, req)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, scope)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Job
+		if err = scanJob(rows, &item); err != nil {
+			return response, err
+		}
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("jobs").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Cancel cancels a queued job at once. A running job is only flagged; the
+// worker running it stops it at its next heartbeat.
+func (r *JobRepo) Cancel(ctx context.Context, req entity.Id) (entity.Job, error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	qeury := `UPDATE jobs SET
+			state = CASE WHEN state = 'queued' THEN 'cancelled' ELSE state END,
+			finished_at = CASE WHEN state = 'queued' THEN now() ELSE finished_at END,
+			input = CASE WHEN state = 'queued' THEN NULL ELSE input END,
+			cancel_requested = true,
+			updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND state IN ('queued', 'running')
+		RETURNING ` + jobColumns
+
+	var response entity.Job
+
+	err = scanJob(r.pg.Pool.QueryRow(ctx, qeury, req.ID, scope["tenant_id"]), &response)
+	if errors.Is(err, pgx.ErrNoRows) {
+		job, err := r.GetSingle(ctx, req)
+		if err != nil {
+			return entity.Job{}, err
+		}
+
+		return entity.Job{}, fmt.Errorf(config.ErrorConflict+"job is already %s", job.State)
+	}
+	if err != nil {
+		return entity.Job{}, err
+	}
+
+	return response, nil
+}
+
+// Claim locks the oldest due job for worker for the lease duration and
+// counts an attempt. Running jobs whose lease expired, because their worker
+// died, are claimed again. ok is false when no job is due.
+func (r *JobRepo) Claim(ctx context.Context, worker string, kinds []string, lease time.Duration) (job entity.Job, ok bool, err error) {
+	qeury := `UPDATE jobs SET
+			state = 'running',
+			attempts = attempts + 1,
+			locked_by = $1,
+			locked_until = now() + $2::bigint * interval '1 millisecond',
+			started_at = COALESCE(started_at, now()),
+			updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE kind = ANY($3)
+				AND ((state = 'queued' AND run_at <= now()) OR (state = 'running' AND locked_until < now()))
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns + `, input`
+
+	err = scanJob(r.pg.Pool.QueryRow(ctx, qeury, worker, lease.Milliseconds(), kinds), &job, &job.Input)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Job{}, false, nil
+	}
+	if err != nil {
+		return entity.Job{}, false, err
+	}
+
+	return job, true, nil
+}
+
+// Heartbeat extends the lease of a running job and stores its progress. It
+// reports whether cancellation was requested, and pgx.ErrNoRows when the
+// worker lost the job, e.g. after its lease expired and another worker took over.
+func (r *JobRepo) Heartbeat(ctx context.Context, id, worker string, progress entity.JobProgress, lease time.Duration) (cancelRequested bool, err error) {
+	err = r.pg.Pool.QueryRow(ctx, `UPDATE jobs SET
+			locked_until = now() + $3::bigint * interval '1 millisecond',
+			progress_done = $4,
+			progress_total = $5,
+			updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND state = 'running'
+		RETURNING cancel_requested`,
+		id, worker, lease.Milliseconds(), progress.Done, progress.Total).Scan(&cancelRequested)
+
+	return cancelRequested, err
+}
+
+// Finish moves a running job into a final state with its result or error.
+func (r *JobRepo) Finish(ctx context.Context, id, worker, state string, result json.RawMessage, message string) error {
+	switch state {
+	case entity.JobSucceeded, entity.JobFailed, entity.JobCancelled:
+	default:
+		return fmt.Errorf("JobRepo - Finish - %q is not a final state", state)
+	}
+
+	var resultJSON []byte
+	if len(result) > 0 {
+		resultJSON = result
+	}
+
+	return r.release(ctx, `state = $3, result = $4, error = $5, input = NULL, finished_at = now()`,
+		id, worker, state, resultJSON, message)
+}
+
+// Retry queues a failed attempt of a running job again after delay. The
+// result of the attempt, if any, is kept until the next one finishes.
+func (r *JobRepo) Retry(ctx context.Context, id, worker, message string, result json.RawMessage, delay time.Duration) error {
+	var resultJSON []byte
+	if len(result) > 0 {
+		resultJSON = result
+	}
+
+	return r.release(ctx, `state = 'queued', error = $3, result = $4, run_at = now() + $5::bigint * interval '1 millisecond'`,
+		id, worker, message, resultJSON, delay.Milliseconds())
+}
+
+// Requeue hands a running job back to the queue without counting the
+// attempt, e.g. when its worker shuts down.
+func (r *JobRepo) Requeue(ctx context.Context, id, worker string) error {
+	return r.release(ctx, `state = 'queued', attempts = GREATEST(attempts - 1, 0), run_at = now()`, id, worker)
+}
+
+// release applies set to the job if worker still holds it and unlocks it.
+// The job id and worker are $1 and $2.
+func (r *JobRepo) release(ctx context.Context, set, id, worker string, args ...interface{}) error {
+	n, err := r.pg.Pool.Exec(ctx, `UPDATE jobs SET `+set+`,
+			locked_by = NULL,
+			locked_until = NULL,
+			updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND state = 'running'`,
+		append([]interface{}{id, worker}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	if n.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
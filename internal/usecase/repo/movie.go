@@ -3,7 +3,9 @@ package repo
 import (
 	"context"
 	"fmt"
-	"log"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,67 +14,246 @@ import (
 	"github.com/abdulazizax/ai-embedding/internal/entity"
 	"github.com/abdulazizax/ai-embedding/pkg/logger"
 	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/progress"
+	"github.com/abdulazizax/ai-embedding/pkg/rerank"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 	openai "github.com/sashabaranov/go-openai"
 )
 
 type MovieRepo struct {
 	openaiClient *openai.Client
+	reranker     rerank.Interface
 	pg           *postgres.Postgres
 	config       *config.Config
 	logger       *logger.Logger
+	document     *documentTemplate
+	cursors      *cursorCodec
 }
 
 // New -.
-func NewMovieRepo(openaiClient *openai.Client, pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *MovieRepo {
+func NewMovieRepo(openaiClient *openai.Client, reranker rerank.Interface, pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *MovieRepo {
+	document, err := newDocumentTemplate(config.Embedding.Template)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("repo - NewMovieRepo - newDocumentTemplate: %w", err))
+	}
+
+	cursors, err := newCursorCodec(config.Cursor.Secret)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("repo - NewMovieRepo - newCursorCodec: %w", err))
+	}
+	if config.Cursor.Secret == "" {
+		logger.Warn("repo - NewMovieRepo - CURSOR_SECRET is not set, pagination cursors are only valid on this instance until restart")
+	}
+
 	return &MovieRepo{
 		openaiClient: openaiClient,
+		reranker:     reranker,
 		pg:           pg,
 		config:       config,
 		logger:       logger,
+		document:     document,
+		cursors:      cursors,
+	}
+}
+
+// trashPurgeBatch is the number of movies PurgeTrash deletes per statement.
+const trashPurgeBatch = 500
+
+// movieColumns are the columns read by scanMovie, in order.
+const movieColumns = `id, slug, name_uz, name_en, name_ru, aliases, description_uz, description_en, description_ru,
+	release_year, runtime_minutes, country, poster_url, created_at, updated_at, deleted_at, version, external_source, external_id,
+	embedding_status, embedding_error`
+
+// scanMovie scans movieColumns followed by extra destinations into movie.
+func scanMovie(row pgx.Row, movie *entity.Movie, extra ...interface{}) error {
+	var (
+		createdAt, updatedAt       time.Time
+		deletedAt                  *time.Time
+		externalSource, externalID *string
+	)
+
+	dest := append([]interface{}{
+		&movie.ID, &movie.Slug, &movie.NameUz, &movie.NameEn, &movie.NameRu, &movie.Aliases,
+		&movie.DescriptionUz, &movie.DescriptionEn, &movie.DescriptionRu,
+		&movie.ReleaseYear, &movie.RuntimeMinutes, &movie.Country, &movie.PosterURL,
+		&createdAt, &updatedAt, &deletedAt, &movie.Version, &externalSource, &externalID,
+		&movie.EmbeddingStatus, &movie.EmbeddingError,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	movie.CreatedAt = createdAt.Format(time.RFC3339)
+	movie.UpdatedAt = updatedAt.Format(time.RFC3339)
+	if deletedAt != nil {
+		movie.DeletedAt = deletedAt.Format(time.RFC3339)
+	}
+	if externalSource != nil && externalID != nil {
+		movie.ExternalSource, movie.ExternalID = *externalSource, *externalID
+	}
+
+	return nil
+}
+
+// scanMovies reads all rows of a movieColumns query.
+func scanMovies(rows pgx.Rows, err error) ([]entity.Movie, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movies []entity.Movie
+	for rows.Next() {
+		var movie entity.Movie
+		if err = scanMovie(rows, &movie); err != nil {
+			return nil, err
+		}
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
+// checkVersion fails with PRECONDITION_FAILED when an expected version was
+// given and the stored one differs.
+func checkVersion(expected, current int) error {
+	if expected != 0 && expected != current {
+		return fmt.Errorf(config.ErrorPrecondition+"movie has version %d, expected %d", current, expected)
+	}
+
+	return nil
+}
+
+// notDeleted and inTrash select movies outside and inside the trash.
+var (
+	notDeleted = squirrel.Eq{"deleted_at": nil}
+	inTrash    = squirrel.NotEq{"deleted_at": nil}
+)
+
+// movieValues maps the stored, user-editable columns of movie to their values.
+func movieValues(movie entity.Movie) map[string]interface{} {
+	return map[string]interface{}{
+		"slug":            movie.Slug,
+		"name_uz":         movie.NameUz,
+		"name_en":         movie.NameEn,
+		"name_ru":         movie.NameRu,
+		"aliases":         movie.Aliases,
+		"description_uz":  movie.DescriptionUz,
+		"description_en":  movie.DescriptionEn,
+		"description_ru":  movie.DescriptionRu,
+		"release_year":    movie.ReleaseYear,
+		"runtime_minutes": movie.RuntimeMinutes,
+		"country":         movie.Country,
+		"poster_url":      movie.PosterURL,
 	}
 }
 
 func (r *MovieRepo) Create(ctx context.Context, req entity.Movie) (entity.Movie, error) {
 	req.ID = uuid.NewString()
+	req.Aliases = cleanPhrases(req.Aliases)
+	// External ids are set through UpsertExternal and imports only.
+	req.ExternalSource, req.ExternalID = "", ""
 
-	embedding, err := r.generateVector(&req)
+	err := r.resolveRelations(ctx, &req)
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
-	formattedEmbedding := formatVectorLiteral(embedding)
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// Movies in the trash still hold their embeddings and count until purged.
+	if err = checkQuota(ctx, tx, "movies", "max_movies"); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if req, err = r.insert(ctx, tx, req); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Movie{}, err
+	}
 
-	qeury, args, err := r.pg.Builder.Insert("movies").
-		Columns(`id, name_uz, name_en, name_ru, embedding`).
-		Values(req.ID, req.NameUz, req.NameEn, req.NameRu, formattedEmbedding).ToSql()
+	return req, nil
+}
+
+// insert stores the new movie req with its relations and revision inside tx
+// and queues it for the embedding workers.
+func (r *MovieRepo) insert(ctx context.Context, tx pgx.Tx, req entity.Movie) (entity.Movie, error) {
+	if err := r.assignSlug(ctx, tx, &req, nil); err != nil {
+		return entity.Movie{}, err
+	}
+
+	// The embedding is generated by the embedding workers after commit.
+	mp := movieValues(req)
+	mp["id"] = req.ID
+	mp["tenant_id"] = tenant.ID(ctx)
+	if req.ExternalID != "" {
+		mp["external_source"], mp["external_id"] = req.ExternalSource, req.ExternalID
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("movies").SetMap(mp).
+		Suffix("RETURNING created_at, updated_at, version").ToSql()
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
-	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	var createdAt, updatedAt time.Time
+
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt, &req.Version)
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
+	if err = r.saveRelations(ctx, tx, req); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = queueEmbeddings(ctx, tx, []string{req.ID}); err != nil {
+		return entity.Movie{}, err
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+	req.EmbeddingStatus, req.EmbeddingError = entity.EmbeddingPending, ""
+
+	if err = r.recordRevision(ctx, tx, revisionCreate, nil, req); err != nil {
+		return entity.Movie{}, err
+	}
+
 	return req, nil
 }
 
 func (r *MovieRepo) GetSingle(ctx context.Context, req entity.MovieSingleRequest) (entity.Movie, error) {
 	response := entity.Movie{}
-	var (
-		createdAt, updatedAt time.Time
-	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, name_uz, name_uz, name_uz, created_at, updated_at`).
+		Select(movieColumns).
 		From("movies")
 
-	filters := squirrel.And{}
+	filters := squirrel.And{scope, notDeleted}
 
 	if req.ID != "" {
 		filters = append(filters, squirrel.Eq{"id": req.ID})
 	}
+	if req.Slug != "" {
+		filters = append(filters, squirrel.Or{
+			squirrel.Eq{"slug": req.Slug},
+			squirrel.Expr("id = (SELECT movie_id FROM movie_slug_history WHERE tenant_id = ? AND slug = ?)", scope["tenant_id"], req.Slug),
+		})
+	}
 	if req.NameUz != "" {
 		filters = append(filters, squirrel.ILike{"name_uz": req.NameUz})
 	}
@@ -83,7 +264,7 @@ func (r *MovieRepo) GetSingle(ctx context.Context, req entity.MovieSingleRequest
 		filters = append(filters, squirrel.ILike{"name_en": req.NameEn})
 	}
 
-	if len(filters) == 0 {
+	if len(filters) == 2 {
 		return entity.Movie{}, fmt.Errorf("GetSingle - invalid request")
 	}
 
@@ -95,31 +276,98 @@ func (r *MovieRepo) GetSingle(ctx context.Context, req entity.MovieSingleRequest
 		return entity.Movie{}, err
 	}
 
-	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
-		Scan(&response.ID, &response.NameUz, &response.NameEn, &response.NameRu, &createdAt, &updatedAt)
+	err = scanMovie(r.pg.Pool.QueryRow(ctx, qeury, args...), &response)
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
-	response.CreatedAt = createdAt.Format(time.RFC3339)
-	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+	movies := []entity.Movie{response}
+	if err = r.loadRelations(ctx, movies); err != nil {
+		return entity.Movie{}, err
+	}
 
-	return response, nil
+	return movies[0], nil
 }
 
 func (r *MovieRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.MovieList, error) {
+	return r.list(ctx, req, notDeleted)
+}
+
+// GetTrash lists the soft-deleted movies that have not been purged yet.
+func (r *MovieRepo) GetTrash(ctx context.Context, req entity.GetListFilter) (entity.MovieList, error) {
+	return r.list(ctx, req, inTrash)
+}
+
+func (r *MovieRepo) list(ctx context.Context, req entity.GetListFilter, state squirrel.Sqlizer) (entity.MovieList, error) {
 	var (
-		response             = entity.MovieList{}
-		createdAt, updatedAt time.Time
+		response = entity.MovieList{}
+		relation squirrel.And
+		keys     [][]*string
 	)
 
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	stateSql, _, err := state.ToSql()
+	if err != nil {
+		return response, err
+	}
+	pageScope := cursorScope(stateSql, req.Filters, req.OrderBy)
+
+	req.Filters, relation, err = relationFilters(req.Filters)
+	if err != nil {
+		return response, err
+	}
+	relation = append(relation, scope, state)
+
+	where, err := PrepareFilter(movieFields, req.Filters)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, relation...)
+
+	// id breaks ties so keyset pages neither repeat nor skip movies.
+	sortBy, err := sortKeys(movieFields, req.OrderBy)
+	if err != nil {
+		return response, err
+	}
+	sortBy = append(sortBy, keysetKey{expr: "id"})
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, name_uz, name_en, name_ru, created_at, updated_at`).
-		From("movies")
+		Select(movieColumns).
+		Columns(keysetColumns(sortBy)...).
+		From("movies").
+		Where(where)
+
+	// Offset mode is kept for existing clients; a cursor continues from the
+	// sort key it carries instead.
+	backward, previous := false, req.Page > 1
+	if req.Cursor != "" {
+		cur, err := r.cursors.decode(req.Cursor, pageScope, len(sortBy))
+		if err != nil {
+			return response, err
+		}
 
-	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+		backward, previous = cur.Prev, true
+		qeuryBuilder = qeuryBuilder.Where(keysetCondition(sortBy, cur.Values, backward))
+	} else {
+		qeuryBuilder = qeuryBuilder.Offset(uint64((req.Page - 1) * req.Limit))
+	}
 
-	qeury, args, err := qeuryBuilder.ToSql()
+	qeury, args, err := qeuryBuilder.
+		OrderBy(keysetOrder(sortBy, backward)...).
+		Limit(uint64(req.Limit + 1)).
+		ToSql()
 	if err != nil {
 		return response, err
 	}
@@ -131,16 +379,43 @@ func (r *MovieRepo) GetList(ctx context.Context, req entity.GetListFilter) (enti
 	defer rows.Close()
 
 	for rows.Next() {
-		var item entity.Movie
-		err = rows.Scan(&item.ID, &item.NameUz, &item.NameEn, &item.NameRu, &createdAt, &updatedAt)
+		var (
+			item entity.Movie
+			key  = make([]*string, len(sortBy))
+			dest = make([]interface{}, len(sortBy))
+		)
+		for i := range key {
+			dest[i] = &key[i]
+		}
+
+		err = scanMovie(rows, &item, dest...)
 		if err != nil {
 			return response, err
 		}
 
-		item.CreatedAt = createdAt.Format(time.RFC3339)
-		item.UpdatedAt = updatedAt.Format(time.RFC3339)
-
 		response.Items = append(response.Items, item)
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	more := len(response.Items) > req.Limit
+	if more {
+		response.Items, keys = response.Items[:req.Limit], keys[:req.Limit]
+	}
+	if backward {
+		reverseMovies(response.Items, keys)
+	}
+
+	response.NextCursor, response.PrevCursor, err = r.cursors.links(pageScope, keys, backward, more, previous)
+	if err != nil {
+		return response, err
+	}
+
+	if err = r.loadRelations(ctx, response.Items); err != nil {
+		return response, err
 	}
 
 	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("movies").Where(where).ToSql()
@@ -153,132 +428,781 @@ func (r *MovieRepo) GetList(ctx context.Context, req entity.GetListFilter) (enti
 		return response, err
 	}
 
+	response.Facets, err = r.facets(ctx, req.Facets, where)
+	if err != nil {
+		return response, err
+	}
+
 	return response, nil
 }
 
+// reverseMovies reverses movies and their sort keys in place, turning a
+// backward window into sort order.
+func reverseMovies(movies []entity.Movie, keys [][]*string) {
+	for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+		movies[i], movies[j] = movies[j], movies[i]
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+}
+
 func (r *MovieRepo) Update(ctx context.Context, req entity.Movie) (entity.Movie, error) {
-	mp := map[string]interface{}{
-		"name_uz":    req.NameUz,
-		"name_en":    req.NameEn,
-		"name_ru":    req.NameRu,
-		"updated_at": "now()",
+	return r.update(ctx, req, revisionUpdate, nil)
+}
+
+// update replaces the content of a movie and records it as a revision with
+// the given action. When base, the stored movie req was derived from, is
+// given and both render to the same document, the embedding is kept.
+func (r *MovieRepo) update(ctx context.Context, req entity.Movie, action string, base *entity.Movie) (entity.Movie, error) {
+	req.Aliases = cleanPhrases(req.Aliases)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Movie{}, err
 	}
 
-	qeury, args, err := r.pg.Builder.Update("movies").SetMap(mp).Where("id = ?", req.ID).ToSql()
+	err = r.resolveRelations(ctx, &req)
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
-	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	reembed, err := r.documentChanged(base, &req)
 	if err != nil {
 		return entity.Movie{}, err
 	}
 
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	previous, err := r.lockMovie(ctx, tx, req.ID, scope, notDeleted)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = checkVersion(req.Version, previous.Version); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if req, err = r.save(ctx, tx, req, &previous, action, reembed); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Movie{}, err
+	}
+
 	return req, nil
 }
 
+// save writes req over previous, the movie locked in tx, taking it out of
+// the trash, and records the revision with the given action. The movie is
+// queued for the embedding workers when reembed is set.
+func (r *MovieRepo) save(ctx context.Context, tx pgx.Tx, req entity.Movie, previous *entity.Movie, action string, reembed bool) (entity.Movie, error) {
+	req.ExternalSource, req.ExternalID = previous.ExternalSource, previous.ExternalID
+
+	if err := r.assignSlug(ctx, tx, &req, previous); err != nil {
+		return entity.Movie{}, err
+	}
+
+	mp := movieValues(req)
+	mp["updated_at"] = "now()"
+	mp["version"] = squirrel.Expr("version + 1")
+	mp["deleted_at"] = nil
+
+	qeury, args, err := r.pg.Builder.Update("movies").SetMap(mp).Where("id = ?", req.ID).
+		Suffix("RETURNING created_at, updated_at, version, embedding_status, embedding_error").ToSql()
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt, &req.Version, &req.EmbeddingStatus, &req.EmbeddingError)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = r.saveRelations(ctx, tx, req); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if reembed {
+		if err = queueEmbeddings(ctx, tx, []string{req.ID}); err != nil {
+			return entity.Movie{}, err
+		}
+		req.EmbeddingStatus, req.EmbeddingError = entity.EmbeddingPending, ""
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+	req.DeletedAt = ""
+
+	if err = r.recordRevision(ctx, tx, action, previous, req); err != nil {
+		return entity.Movie{}, err
+	}
+
+	return req, nil
+}
+
+// Delete moves a movie to the trash. It keeps its embedding and slug until it
+// is restored or purged.
 func (r *MovieRepo) Delete(ctx context.Context, req entity.Id) error {
-	qeury, args, err := r.pg.Builder.Delete("movies").Where("id = ?", req.ID).ToSql()
+	scope, err := tenantScope(ctx)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	tx, err := r.pg.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx) //nolint:errcheck
 
-	return nil
+	previous, err := r.lockMovie(ctx, tx, req.ID, scope, notDeleted)
+	if err == pgx.ErrNoRows && req.Version == 0 {
+		return nil
+	}
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf(config.ErrorPrecondition + "movie does not exist")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = checkVersion(req.Version, previous.Version); err != nil {
+		return err
+	}
+
+	movie := previous
+
+	var deletedAt time.Time
+
+	err = tx.QueryRow(ctx, `UPDATE movies SET deleted_at = now(), version = version + 1 WHERE id = $1 RETURNING deleted_at, version`, req.ID).
+		Scan(&deletedAt, &movie.Version)
+	if err != nil {
+		return err
+	}
+
+	movie.DeletedAt = deletedAt.Format(time.RFC3339)
+
+	if err = r.recordRevision(ctx, tx, revisionDelete, &previous, movie); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Restore takes a movie out of the trash.
+func (r *MovieRepo) Restore(ctx context.Context, req entity.Id) (entity.Movie, error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	previous, err := r.lockMovie(ctx, tx, req.ID, scope, inTrash)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	movie := previous
+
+	var updatedAt time.Time
+
+	err = tx.QueryRow(ctx, `UPDATE movies SET deleted_at = NULL, updated_at = now(), version = version + 1 WHERE id = $1 RETURNING updated_at, version`, req.ID).
+		Scan(&updatedAt, &movie.Version)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	movie.DeletedAt = ""
+	movie.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	// A movie merged into another one redirected its slug there; it answers
+	// for the slug again.
+	_, err = tx.Exec(ctx, `DELETE FROM movie_slug_history WHERE tenant_id = $1 AND slug = $2`, scope["tenant_id"], movie.Slug)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = r.recordRevision(ctx, tx, revisionRestore, &previous, movie); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Movie{}, err
+	}
+
+	return movie, nil
 }
 
-func (r *MovieRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
-	mp := map[string]interface{}{}
-	response := entity.RowsEffected{}
+// PurgeTrash permanently deletes, for all tenants, the movies that have been
+// in the trash for longer than retention. It works in batches to keep locks short.
+func (r *MovieRepo) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	var total int
+
+	for {
+		n, err := r.pg.Pool.Exec(ctx, `DELETE FROM movies WHERE id IN (
+			SELECT id FROM movies WHERE deleted_at < now() - $1::interval LIMIT $2
+		)`, retention, trashPurgeBatch)
+		if err != nil {
+			return total, err
+		}
+
+		total += int(n.RowsAffected())
+		if n.RowsAffected() < trashPurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+func (r *MovieRepo) Search(ctx context.Context, req entity.MovieSearchRequest) (entity.MovieList, error) {
+	response := entity.MovieList{}
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+
+	tenantID := tenant.ID(ctx)
+	if tenantID == "" {
+		return response, fmt.Errorf("MovieRepo - Search - tenant is not resolved")
+	}
+
+	expansions, err := expandQuery(ctx, r.pg, req.Query)
+	if err != nil {
+		return entity.MovieList{}, err
+	}
+
+	// The embedder and the reranker see the query together with its
+	// expansions; full-text matching accepts any of them.
+	expandedQuery := req.Query
+	textQuery := req.Query
+	for _, expansion := range expansions {
+		expandedQuery += " (" + expansion + ")"
+		textQuery += ` OR "` + strings.ReplaceAll(expansion, `"`, "") + `"`
+	}
+
+	embedding, err := r.embed(ctx, expandedQuery)
+	if err != nil {
+		return entity.MovieList{}, err
+	}
+
+	formattedEmbedding := formatVectorLiteral(embedding)
+
+	// Hits are fetched in windows of fusion order. The reranker reorders a
+	// whole window, which can be wider than a page; pages are then cut from
+	// the reranked window, and a cursor names the window and the offset into
+	// it, so every hit of a window is offered once. The window is reranked
+	// again for each of its pages, which keeps the pages consistent as long
+	// as the reranker orders the same candidates the same way.
+	window := req.Limit
+	pageScope := cursorScope("search", req.Query)
+	if r.reranker != nil {
+		if r.config.Rerank.TopN > window {
+			window = r.config.Rerank.TopN
+		}
+		pageScope = cursorScope("search", req.Query, r.reranker.Name(), window)
+	}
+	sortBy := []keysetKey{{expr: "score", desc: true}, {expr: "id"}}
+
+	// Hits are ranked by a weighted fusion of vector similarity and full-text
+	// rank, so exact title matches are not lost behind semantically close ones.
+	// Movies without a ready embedding, whose vector is missing or belongs to
+	// older content, are ranked by full text alone. The best
+	// Search.MaxResults are the hits; pages, count and facets all cover them.
+	ranked := `SELECT ` + movieColumns + `, distance, text_rank, matched_field,
+			COALESCE(? / (1 + distance), 0) + ? * text_rank AS score
+		FROM (
+			SELECT ` + movieColumns + `,
+				CASE WHEN embedding_status = 'ready' THEN embedding <-> ? END AS distance,
+				ts_rank(to_tsvector('simple', name_uz || ' ' || name_en || ' ' || name_ru), websearch_to_tsquery('simple', ?), 32) AS text_rank,
+				CASE GREATEST(word_similarity(?, name_uz), word_similarity(?, name_en), word_similarity(?, name_ru))
+					WHEN word_similarity(?, name_en) THEN 'name_en'
+					WHEN word_similarity(?, name_uz) THEN 'name_uz'
+					ELSE 'name_ru'
+				END AS matched_field
+			FROM movies
+			WHERE tenant_id = ? AND deleted_at IS NULL
+		) scored
+		ORDER BY score DESC, id
+		LIMIT ?`
+	rankedArgs := []interface{}{
+		r.config.Search.VectorWeight, r.config.Search.TextWeight, formattedEmbedding, textQuery,
+		req.Query, req.Query, req.Query, req.Query, req.Query, tenantID, r.maxResults(),
+	}
+
+	qeury := `WITH hits AS (` + ranked + `)
+	SELECT ` + movieColumns + `,
+		COALESCE(distance, 0), text_rank, matched_field, score, ` + strings.Join(keysetColumns(sortBy), ", ") + `
+	FROM hits`
+	args := append([]interface{}{}, rankedArgs...)
+
+	var cur cursor
+	if req.Cursor != "" {
+		cur, err = r.cursors.decode(req.Cursor, pageScope, len(sortBy))
+		if err != nil {
+			return response, err
+		}
+
+		condition, conditionArgs, err := keysetCondition(sortBy, cur.Values, cur.Prev).ToSql()
+		if err != nil {
+			return response, err
+		}
+
+		qeury += `
+	WHERE ` + condition
+		args = append(args, conditionArgs...)
+	}
+
+	qeury += `
+	ORDER BY ` + strings.Join(keysetOrder(sortBy, cur.Prev), ", ") + `
+	LIMIT ?`
+	args = append(args, window+1)
+	qeury = numberPlaceholders(qeury, 1)
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	var keys [][]*string
+	for rows.Next() {
+		var (
+			item    entity.Movie
+			explain entity.MovieHitExplain
+			key     = make([]*string, len(sortBy))
+		)
+		err = scanMovie(rows, &item, &explain.Distance, &explain.TextRank, &explain.MatchedField, &explain.Score, &key[0], &key[1])
+		if err != nil {
+			return response, err
+		}
+
+		item.Distance = explain.Distance
 
-	for _, item := range req.Items {
-		mp[item.Column] = item.Value
+		if req.Explain {
+			item.Explain = &explain
+		}
+
+		response.Items = append(response.Items, item)
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return response, err
 	}
 
-	qeury, args, err := r.pg.Builder.Update("movies").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	rerankErr, err := r.searchPage(ctx, &response, keys, cur, expandedQuery, pageScope, window, req.Limit)
 	if err != nil {
 		return response, err
 	}
 
-	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	// Every movie outside the trash is ranked, so the hits are all of them
+	// up to Search.MaxResults.
+	err = r.pg.Pool.QueryRow(ctx, `SELECT COUNT(1) FROM movies WHERE tenant_id = $1 AND deleted_at IS NULL`, tenantID).
+		Scan(&response.Count)
 	if err != nil {
 		return response, err
 	}
+	response.Count = min(response.Count, r.maxResults())
 
-	response.RowsEffected = int(n.RowsAffected())
+	if err = r.loadRelations(ctx, response.Items); err != nil {
+		return response, err
+	}
+
+	// Facets count the same hits the pages are cut from.
+	response.Facets, err = r.facets(ctx, req.Facets, squirrel.And{
+		squirrel.Eq{"tenant_id": tenantID},
+		squirrel.Expr(`id IN (SELECT id FROM (`+ranked+`) hits)`, rankedArgs...),
+	})
+	if err != nil {
+		return response, err
+	}
+
+	if req.Explain {
+		response.Explain = &entity.SearchExplain{
+			Model:        r.config.OpenAI.EmbeddingModel,
+			Dimensions:   len(embedding),
+			VectorWeight: r.config.Search.VectorWeight,
+			TextWeight:   r.config.Search.TextWeight,
+			Expansions:   expansions,
+		}
+
+		if r.reranker != nil {
+			response.Explain.Reranker = r.reranker.Name()
+		}
+		if rerankErr != nil {
+			response.Explain.RerankError = rerankErr.Error()
+		}
+
+		response.Explain.Plan, response.Explain.PlanCost, err = r.explainPlan(ctx, qeury, args...)
+		if err != nil {
+			return response, err
+		}
+	}
 
 	return response, nil
 }
 
-func (r *MovieRepo) Search(ctx context.Context, req entity.MovieSingleRequest) (entity.MovieList, error) {
+// searchPage reranks a window of hits fetched for cur and cuts from it the
+// page of limit hits cur points to, setting the items and cursors of
+// response. The fetch returned response.Items with their sort keys in fetch
+// order and asked for one hit more than window, to tell whether there are
+// hits beyond it. rerankErr is the error of the reranker, which leaves the
+// window in fusion order.
+func (r *MovieRepo) searchPage(ctx context.Context, response *entity.MovieList, keys [][]*string, cur cursor, query, scope string, window, limit int) (rerankErr, err error) {
+	// start is the sort key of the hit before the window, empty for the
+	// first window.
 	var (
-		response             = entity.MovieList{}
-		createdAt, updatedAt time.Time
-		count                int
+		start         []*string
+		before, after bool
+		backward      = cur.Prev
+		offset        = cur.Offset
+		more          = len(response.Items) > window
 	)
+	if more {
+		if backward {
+			start = keys[window]
+		}
+		response.Items, keys = response.Items[:window], keys[:window]
+	}
+	if backward {
+		// Paging back into a window lands on its last page.
+		reverseMovies(response.Items, keys)
+		before, after = more, true
+		offset = 0
+		if len(response.Items) > 0 {
+			offset = (len(response.Items) - 1) / limit * limit
+		}
+	} else {
+		start = cur.Values
+		before, after = len(start) > 0, more
+	}
 
-	embedding, err := r.generateVector(&entity.Movie{
-		NameUz: req.NameUz,
-		NameEn: req.NameEn,
-		NameRu: req.NameRu,
-	})
+	for i := range response.Items {
+		if response.Items[i].Explain != nil {
+			response.Items[i].Explain.FusionRank = i + 1
+		}
+	}
+
+	rerankErr = r.rerankMovies(ctx, query, response.Items)
+
+	if offset > len(response.Items) {
+		offset = len(response.Items)
+	}
+	end := offset + limit
+	if end > len(response.Items) {
+		end = len(response.Items)
+	}
+
+	switch {
+	case end < len(response.Items):
+		response.NextCursor, err = r.cursors.encode(cursor{Scope: scope, Values: start, Offset: end})
+	case after && len(keys) > 0:
+		response.NextCursor, err = r.cursors.encode(cursor{Scope: scope, Values: keys[len(keys)-1]})
+	}
 	if err != nil {
-		return entity.MovieList{}, err
+		return rerankErr, err
 	}
 
-	formattedEmbedding := formatVectorLiteral(embedding)
+	switch {
+	case offset > 0:
+		response.PrevCursor, err = r.cursors.encode(cursor{Scope: scope, Values: start, Offset: max(offset-limit, 0)})
+	case before && len(keys) > 0:
+		response.PrevCursor, err = r.cursors.encode(cursor{Scope: scope, Values: keys[0], Prev: true})
+	}
+	if err != nil {
+		return rerankErr, err
+	}
 
-	qeuryBuilder := r.pg.Builder.
-		Select(`id, name_uz, name_en, name_ru, created_at, updated_at, embedding <-> ? AS distance`).
-		From("movies").
-		OrderBy("distance").
-		Limit(10)
+	response.Items = response.Items[offset:end]
+
+	return rerankErr, nil
+}
+
+// rerankMovies reorders items in place by reranker score. When the reranker
+// is disabled, fails or times out, the fusion order is kept and the error is
+// returned for reporting only.
+func (r *MovieRepo) rerankMovies(ctx context.Context, query string, items []entity.Movie) error {
+	if r.reranker == nil || len(items) < 2 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Rerank.Timeout)
+	defer cancel()
+
+	docs := make([]rerank.Document, len(items))
+	for i, item := range items {
+		docs[i] = rerank.Document{
+			ID:   item.ID,
+			Text: strings.Join([]string{item.NameEn, item.NameUz, item.NameRu}, " / "),
+		}
+	}
 
-	qeury, _, err := qeuryBuilder.ToSql()
+	scores, err := r.reranker.Rerank(ctx, query, docs)
+	if err == nil && len(scores) != len(items) {
+		err = fmt.Errorf("got %d scores for %d candidates", len(scores), len(items))
+	}
 	if err != nil {
-		return response, err
+		r.logger.Warn("MovieRepo - rerankMovies - %s: %v", r.reranker.Name(), err)
+		return err
+	}
+
+	for i := range items {
+		if items[i].Explain != nil {
+			items[i].Explain.RerankScore = scores[i]
+		}
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
 	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
 
-	rows, err := r.pg.Pool.Query(ctx, qeury, formattedEmbedding)
+	reranked := make([]entity.Movie, len(items))
+	for i, idx := range order {
+		reranked[i] = items[idx]
+	}
+	copy(items, reranked)
+
+	return nil
+}
+
+func (r *MovieRepo) Suggest(ctx context.Context, req entity.MovieSuggestRequest) (entity.MovieSuggestList, error) {
+	response := entity.MovieSuggestList{Items: []entity.MovieSuggestion{}}
+
+	tenantID := tenant.ID(ctx)
+	if tenantID == "" {
+		return response, fmt.Errorf("MovieRepo - Suggest - tenant is not resolved")
+	}
+
+	langs := []string{"uz", "en", "ru"}
+	if req.Lang != "" {
+		if _, ok := suggestColumns[req.Lang]; !ok {
+			return response, fmt.Errorf(config.ErrorBadRequest+"unsupported lang %q", req.Lang)
+		}
+		langs = []string{req.Lang}
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+
+	// Every branch is served by the trigram index on its column: ILIKE covers
+	// the title prefix, <% covers a prefix of any word inside the title.
+	branches := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		column := suggestColumns[lang]
+		branches = append(branches, fmt.Sprintf(
+			`SELECT id, '%[1]s' AS lang, %[2]s AS title, %[2]s ILIKE $1 AS is_prefix, word_similarity($2, %[2]s) AS score
+			FROM movies WHERE tenant_id = $4 AND deleted_at IS NULL AND (%[2]s ILIKE $1 OR $2 <%% %[2]s)`, lang, column))
+	}
+
+	qeury := `SELECT id, lang, title, score FROM (
+		SELECT DISTINCT ON (id) id, lang, title, is_prefix, score
+		FROM (` + strings.Join(branches, " UNION ALL ") + `) candidates
+		ORDER BY id, is_prefix DESC, score DESC
+	) suggestions
+	ORDER BY is_prefix DESC, score DESC, title
+	LIMIT $3`
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, escapeLike(req.Query)+"%", req.Query, req.Limit, tenantID)
 	if err != nil {
 		return response, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var item entity.Movie
-		err = rows.Scan(&item.ID, &item.NameUz, &item.NameEn, &item.NameRu, &createdAt, &updatedAt, &item.Distance)
+		var item entity.MovieSuggestion
+		err = rows.Scan(&item.ID, &item.Lang, &item.Title, &item.Score)
 		if err != nil {
 			return response, err
 		}
 
-		item.CreatedAt = createdAt.Format(time.RFC3339)
-		item.UpdatedAt = updatedAt.Format(time.RFC3339)
-
 		response.Items = append(response.Items, item)
-		count++
 	}
 
-	response.Count = count
+	return response, rows.Err()
+}
 
-	return response, nil
+// documentChanged reports whether movie renders to a different embedding
+// document than base; without a base it always does.
+func (r *MovieRepo) documentChanged(base, movie *entity.Movie) (bool, error) {
+	if base == nil {
+		return true, nil
+	}
+
+	before, err := r.document.Render(base)
+	if err != nil {
+		return false, err
+	}
+
+	after, err := r.document.Render(movie)
+	if err != nil {
+		return false, err
+	}
+
+	return before != after, nil
 }
 
-func (r *MovieRepo) generateVector(movie *entity.Movie) ([]float32, error) {
-	req := openai.EmbeddingRequest{
-		Input: []string{movie.NameUz, movie.NameEn, movie.NameRu},
-		Model: openai.AdaEmbeddingV2,
+// ReembedStale embeds again every movie whose embedding was produced by a
+// different document template than the configured one, in batches, and
+// returns the number of movies updated.
+func (r *MovieRepo) ReembedStale(ctx context.Context) (int, error) {
+	var total int
+
+	batch := r.config.Embedding.ReembedBatch
+	if batch <= 0 {
+		batch = 100
 	}
-	resp, err := r.openaiClient.CreateEmbeddings(context.Background(), req)
+
+	for {
+		qeury, args, err := r.pg.Builder.
+			Select(movieColumns).
+			From("movies").
+			Where("embedding_template_hash <> ?", r.document.Hash()).
+			Where("embedding_status <> ?", entity.EmbeddingPending).
+			Where(notDeleted).
+			OrderBy("id").
+			Limit(uint64(batch)).ToSql()
+		if err != nil {
+			return total, err
+		}
+
+		rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+		if err != nil {
+			return total, err
+		}
+
+		var movies []entity.Movie
+		for rows.Next() {
+			var item entity.Movie
+			if err = scanMovie(rows, &item); err != nil {
+				rows.Close()
+				return total, err
+			}
+			movies = append(movies, item)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return total, err
+		}
+
+		if len(movies) == 0 {
+			return total, nil
+		}
+
+		if err = r.loadRelations(ctx, movies); err != nil {
+			return total, err
+		}
+
+		if err = r.reembedMovies(ctx, movies); err != nil {
+			return total, err
+		}
+
+		total += len(movies)
+		progress.Report(ctx, total, 0)
+	}
+}
+
+// reembedMovies embeds movies in batches of Embedding.ReembedBatch documents
+// and stores the vectors together with the current template hash, flagging
+// the movies they are near duplicates of. Movies queued for the embedding workers meanwhile are left to them.
+func (r *MovieRepo) reembedMovies(ctx context.Context, movies []entity.Movie) error {
+	batch := r.config.Embedding.ReembedBatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	documents := make([]string, len(movies))
+	for i := range movies {
+		document, err := r.document.Render(&movies[i])
+		if err != nil {
+			return fmt.Errorf("MovieRepo - reembedMovies - %w", err)
+		}
+		documents[i] = document
+	}
+
+	for start := 0; start < len(movies); start += batch {
+		end := start + batch
+		if end > len(movies) {
+			end = len(movies)
+		}
+
+		vectors, err := createEmbeddings(ctx, r.openaiClient, r.config.OpenAI.EmbeddingModel, documents[start:end], embeddingDimensions)
+		if err != nil {
+			return fmt.Errorf("MovieRepo - reembedMovies - %w", err)
+		}
+
+		tx, err := r.pg.Pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		stored := make([]string, 0, len(vectors))
+		for i, vector := range vectors {
+			qeury, args, err := r.pg.Builder.Update("movies").
+				SetMap(map[string]interface{}{
+					"embedding":               formatVectorLiteral(vector),
+					"embedding_template_hash": r.document.Hash(),
+					"embedding_status":        entity.EmbeddingReady,
+					"embedding_error":         "",
+				}).
+				Where("id = ?", movies[start+i].ID).
+				Where("embedding_status <> ?", entity.EmbeddingPending).ToSql()
+			if err != nil {
+				return err
+			}
+
+			tag, err := tx.Exec(ctx, qeury, args...)
+			if err != nil {
+				tx.Rollback(ctx) //nolint:errcheck
+				return err
+			}
+			if tag.RowsAffected() > 0 {
+				stored = append(stored, movies[start+i].ID)
+			}
+		}
+
+		if err = r.flagDuplicates(ctx, tx, stored); err != nil {
+			tx.Rollback(ctx) //nolint:errcheck
+			return err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxResults returns Search.MaxResults, 1000 when it is not set.
+func (r *MovieRepo) maxResults() int {
+	if r.config.Search.MaxResults <= 0 {
+		return 1000
+	}
+
+	return r.config.Search.MaxResults
+}
+
+// embed returns the embedding of a free-form search query.
+func (r *MovieRepo) embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := createEmbeddings(ctx, r.openaiClient, r.config.OpenAI.EmbeddingModel, []string{text}, embeddingDimensions)
 	if err != nil {
-		log.Fatalf("Embedding generatsiya qilishda xato: %v", err)
+		return nil, fmt.Errorf("MovieRepo - embed - %w", err)
 	}
 
-	return resp.Data[0].Embedding, nil
+	return vectors[0], nil
 }
 
 func formatVectorLiteral(vector []float32) string {
@@ -290,9 +1214,34 @@ func formatVectorLiteral(vector []float32) string {
 	// Convert the vector values to string
 	strValues := make([]string, len(vector))
 	for i, v := range vector {
-		strValues[i] = fmt.Sprintf("%f", v) // Format float32 to string
+		strValues[i] = strconv.FormatFloat(float64(v), 'g', -1, 32) // Shortest form that reads back as v
 	}
 
 	// Join the values with commas and wrap them in square brackets for array
 	return "[" + strings.Join(strValues, ",") + "]"
 }
+
+// parseVectorLiteral reads a vector in the "[x,y,...]" form pgvector prints.
+func parseVectorLiteral(literal string) ([]float32, error) {
+	literal = strings.TrimSpace(literal)
+	if !strings.HasPrefix(literal, "[") || !strings.HasSuffix(literal, "]") {
+		return nil, fmt.Errorf("vector must be written as [x,y,...]")
+	}
+
+	literal = strings.TrimSpace(literal[1 : len(literal)-1])
+	if literal == "" {
+		return []float32{}, nil
+	}
+
+	parts := strings.Split(literal, ",")
+	vector := make([]float32, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("vector element %d is not a finite number", i+1)
+		}
+		vector[i] = float32(v)
+	}
+
+	return vector, nil
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+// Kinds of values a bulk update may write.
+const (
+	kindText      = "text"
+	kindInt       = "int"
+	kindTextArray = "text[]"
+)
+
+// movieUpdatableColumns lists the columns UpdateField may set and the kind of
+// value each one takes.
+var movieUpdatableColumns = map[string]string{
+	"name_uz":         kindText,
+	"name_en":         kindText,
+	"name_ru":         kindText,
+	"aliases":         kindTextArray,
+	"description_uz":  kindText,
+	"description_en":  kindText,
+	"description_ru":  kindText,
+	"release_year":    kindInt,
+	"runtime_minutes": kindInt,
+	"country":         kindText,
+	"poster_url":      kindText,
+}
+
+// bulkValues validates the items of a bulk update against
+// movieUpdatableColumns and converts their JSON values to column values.
+func bulkValues(items []entity.UpdateFieldItem) (map[string]interface{}, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf(config.ErrorBadRequest + "at least one item is required")
+	}
+
+	mp := make(map[string]interface{}, len(items))
+
+	for _, item := range items {
+		kind, ok := movieUpdatableColumns[item.Column]
+		if !ok {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"column %q cannot be updated", item.Column)
+		}
+
+		switch kind {
+		case kindText:
+			value, ok := item.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf(config.ErrorBadRequest+"%s must be a string", item.Column)
+			}
+			mp[item.Column] = value
+		case kindInt:
+			value, ok := item.Value.(float64)
+			if !ok || value != math.Trunc(value) || value < 0 || value > math.MaxInt32 {
+				return nil, fmt.Errorf(config.ErrorBadRequest+"%s must be a non-negative integer", item.Column)
+			}
+			mp[item.Column] = int(value)
+		case kindTextArray:
+			values, ok := item.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf(config.ErrorBadRequest+"%s must be an array of strings", item.Column)
+			}
+
+			phrases := make([]string, 0, len(values))
+			for _, v := range values {
+				phrase, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf(config.ErrorBadRequest+"%s must be an array of strings", item.Column)
+				}
+				phrases = append(phrases, phrase)
+			}
+			mp[item.Column] = cleanPhrases(phrases)
+		}
+	}
+
+	return mp, nil
+}
+
+// bulkFilter builds the condition of a bulk update. Neither the filter nor
+// its values may be empty, so a missing filter or an empty search cannot turn
+// into an update of every movie.
+func bulkFilter(filters []entity.Filter) (squirrel.And, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf(config.ErrorBadRequest + "at least one filter is required")
+	}
+
+	for _, filter := range filters {
+		if filter.Type != "is_null" && strings.TrimSpace(filter.Value) == "" {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"filter on %s needs a value", filter.Column)
+		}
+	}
+
+	rest, where, err := relationFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := PrepareFilter(movieFields, rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(where, conditions...), nil
+}
+
+// UpdateField sets the given columns on every movie matching the filter. In
+// dry-run mode it only counts them. Updates matching more than Bulk.MaxRows
+// movies are rejected before anything is written. Movies whose embedding document changes
+// are queued for the embedding workers in the same transaction.
+func (r *MovieRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	response := entity.RowsEffected{}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	mp, err := bulkValues(req.Items)
+	if err != nil {
+		return response, err
+	}
+
+	filter, err := bulkFilter(req.Filter)
+	if err != nil {
+		return response, err
+	}
+
+	where := squirrel.And{filter, scope, notDeleted}
+
+	if req.DryRun {
+		qeury, args, err := r.pg.Builder.Select("COUNT(1)").From("movies").Where(where).ToSql()
+		if err != nil {
+			return response, err
+		}
+
+		err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&response.RowsEffected)
+		return response, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// The affected movies are locked and read before and after the update so
+	// every one of them gets a revision. One more than allowed is read to
+	// tell when the filter matches too many.
+	builder := r.pg.Builder.Select(movieColumns).From("movies").
+		Where(where).OrderBy("id").Suffix("FOR UPDATE")
+	if maxRows := r.config.Bulk.MaxRows; maxRows > 0 {
+		builder = builder.Limit(uint64(maxRows) + 1)
+	}
+
+	qeury, args, err := builder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	before, err := scanMovies(tx.Query(ctx, qeury, args...))
+	if err != nil {
+		return response, err
+	}
+
+	if maxRows := r.config.Bulk.MaxRows; maxRows > 0 && len(before) > maxRows {
+		return response, fmt.Errorf(config.ErrorBadRequest+"filter matches more than %d movies, narrow it down", maxRows)
+	}
+
+	if len(before) == 0 {
+		return response, nil
+	}
+
+	if err = r.loadRelations(ctx, before); err != nil {
+		return response, err
+	}
+
+	ids := make([]string, len(before))
+	for i := range before {
+		ids[i] = before[i].ID
+	}
+
+	mp["version"] = squirrel.Expr("version + 1")
+	mp["updated_at"] = squirrel.Expr("now()")
+
+	qeury, args, err = r.pg.Builder.Update("movies").SetMap(mp).Where("id = ANY(?)", ids).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	n, err := tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err = r.pg.Builder.Select(movieColumns).From("movies").
+		Where("id = ANY(?)", ids).OrderBy("id").ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	after, err := scanMovies(tx.Query(ctx, qeury, args...))
+	if err != nil {
+		return response, err
+	}
+
+	var stale []entity.Movie
+
+	for i := range after {
+		after[i].Genres = before[i].Genres
+		after[i].Cast = before[i].Cast
+
+		changed, err := r.documentChanged(&before[i], &after[i])
+		if err != nil {
+			return response, err
+		}
+		if changed {
+			stale = append(stale, after[i])
+		}
+
+		if err = r.recordRevision(ctx, tx, revisionUpdateField, &before[i], after[i]); err != nil {
+			return response, err
+		}
+	}
+
+	staleIDs := make([]string, len(stale))
+	for i := range stale {
+		staleIDs[i] = stale[i].ID
+	}
+
+	if err = queueEmbeddings(ctx, tx, staleIDs); err != nil {
+		return response, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return response, err
+	}
+
+	response.RowsEffected = int(n.RowsAffected())
+	response.Reembedded = len(stale)
+
+	return response, nil
+}
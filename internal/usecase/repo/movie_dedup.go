@@ -0,0 +1,636 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/progress"
+	"github.com/jackc/pgx/v4"
+)
+
+// maxMergeDuplicates is the most movies Merge folds into one at a time.
+const maxMergeDuplicates = 50
+
+// dedupNameColumns are the name columns compared between movies; names are
+// only compared within the same language.
+var dedupNameColumns = []string{"name_uz", "name_en", "name_ru"}
+
+// dedupCandidates returns Dedup.Candidates, 5 when it is not set.
+func (r *MovieRepo) dedupCandidates() int {
+	if r.config.Dedup.Candidates <= 0 {
+		return 5
+	}
+
+	return r.config.Dedup.Candidates
+}
+
+// FindDuplicates returns the stored movies outside the trash with a name
+// that has at least Dedup.NameSimilarity trigram similarity to the name of
+// movie in the same language, the best Dedup.Candidates first. Embeddings are
+// not compared here, as movie has none yet: the embedding workers flag
+// vector look-alikes once its vector is stored, and DuplicateReport lists
+// them. Trigram matches are found through the index, so thresholds below
+// pg_trgm.similarity_threshold (0.3 by default) behave like it.
+func (r *MovieRepo) FindDuplicates(ctx context.Context, movie entity.Movie) ([]entity.MovieDuplicate, error) {
+	duplicates := []entity.MovieDuplicate{}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return duplicates, err
+	}
+
+	if r.config.Dedup.NameSimilarity <= 0 {
+		return duplicates, nil
+	}
+
+	args := []interface{}{scope["tenant_id"]}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var scores, filters []string
+	names := map[string]string{"name_uz": movie.NameUz, "name_en": movie.NameEn, "name_ru": movie.NameRu}
+	for _, column := range dedupNameColumns {
+		name := strings.TrimSpace(names[column])
+		if name == "" {
+			continue
+		}
+
+		p := arg(name)
+		scores = append(scores, fmt.Sprintf("similarity(%s, %s)", column, p))
+		filters = append(filters, fmt.Sprintf("%s %% %s", column, p))
+	}
+
+	if len(filters) == 0 {
+		return duplicates, nil
+	}
+
+	qeury := fmt.Sprintf(`SELECT id, slug, name_uz, name_ru, name_en, release_year, name_score FROM (
+		SELECT id, slug, name_uz, name_ru, name_en, release_year, GREATEST(%s)::float8 AS name_score
+		FROM movies WHERE tenant_id = $1 AND deleted_at IS NULL AND (%s)
+	) candidates
+	WHERE name_score >= %s
+	ORDER BY name_score DESC, id
+	LIMIT %s`,
+		strings.Join(scores, ", "), strings.Join(filters, " OR "), arg(r.config.Dedup.NameSimilarity), arg(r.dedupCandidates()))
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return duplicates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.MovieDuplicate
+		err = rows.Scan(&item.ID, &item.Slug, &item.NameUz, &item.NameRu, &item.NameEn, &item.ReleaseYear, &item.NameSimilarity)
+		if err != nil {
+			return duplicates, err
+		}
+		duplicates = append(duplicates, item)
+	}
+
+	return duplicates, rows.Err()
+}
+
+// flagDuplicates replaces the vector look-alikes recorded for the movies ids
+// with the Dedup.Candidates nearest movies of their tenant whose ready
+// embeddings have at least Dedup.VectorSimilarity cosine similarity to theirs.
+// It runs in the tx that stored their embeddings.
+func (r *MovieRepo) flagDuplicates(ctx context.Context, tx pgx.Tx, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `DELETE FROM movie_duplicates WHERE movie_a::text = ANY($1) OR movie_b::text = ANY($1)`, ids)
+	if err != nil || r.config.Dedup.VectorSimilarity <= 0 {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO movie_duplicates (tenant_id, movie_a, movie_b, similarity)
+		SELECT DISTINCT ON (movie_a, movie_b) tenant_id, movie_a, movie_b, similarity FROM (
+			SELECT s.tenant_id, LEAST(s.id, n.id) AS movie_a, GREATEST(s.id, n.id) AS movie_b, n.similarity
+			FROM movies s
+			CROSS JOIN LATERAL (
+				SELECT m.id, (1 - (m.embedding <=> s.embedding))::float8 AS similarity
+				FROM movies m
+				WHERE m.tenant_id = s.tenant_id AND m.id <> s.id AND m.deleted_at IS NULL AND m.embedding_status = $2
+				ORDER BY m.embedding <=> s.embedding
+				LIMIT $3
+			) n
+			WHERE s.id::text = ANY($1) AND s.embedding_status = $2 AND n.similarity >= $4
+		) pairs
+		ORDER BY movie_a, movie_b
+		ON CONFLICT (movie_a, movie_b) DO UPDATE SET similarity = EXCLUDED.similarity, created_at = now()`,
+		ids, entity.EmbeddingReady, r.dedupCandidates(), r.config.Dedup.VectorSimilarity)
+
+	return err
+}
+
+// rescanDuplicates flags the vector look-alikes of every movie of the tenant
+// with a ready embedding again, in batches of Embedding.ReembedBatch, for
+// movies embedded before a threshold change or before they were flagged.
+func (r *MovieRepo) rescanDuplicates(ctx context.Context) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	batch := r.config.Embedding.ReembedBatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	var after string
+	for done := 0; ; {
+		qeury, args, err := r.pg.Builder.Select("id::text").From("movies").
+			Where(scope).
+			Where(squirrel.Eq{"deleted_at": nil, "embedding_status": entity.EmbeddingReady}).
+			Where("id::text > ?", after).
+			OrderBy("id::text").
+			Limit(uint64(batch)).ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		tx, err := r.pg.Pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err = r.flagDuplicates(ctx, tx, ids); err != nil {
+			tx.Rollback(ctx) //nolint:errcheck
+			return err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		done += len(ids)
+		after = ids[len(ids)-1]
+		progress.Report(ctx, done, 0)
+	}
+}
+
+// DuplicateReport groups the movies of the tenant outside the trash into
+// clusters of likely duplicates, connected by pairs whose names pass the
+// check of FindDuplicates or that the embedding workers flagged as vector
+// look-alikes; with req.Rescan every movie is flagged again first. At most
+// Dedup.MaxPairs pairs of each check are clustered. Clusters come with
+// their strongest pair first; Count is the number of clusters before
+// req.Limit is applied.
+func (r *MovieRepo) DuplicateReport(ctx context.Context, req entity.MovieDuplicateReportRequest) (entity.MovieDuplicateReport, error) {
+	response := entity.MovieDuplicateReport{Clusters: []entity.MovieDuplicateCluster{}}
+
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+	tenantID := scope["tenant_id"]
+
+	dedup := r.config.Dedup
+	pairs := map[[2]string]*entity.MovieDuplicatePair{}
+	pair := func(a, b string) *entity.MovieDuplicatePair {
+		if b < a {
+			a, b = b, a
+		}
+		p, ok := pairs[[2]string{a, b}]
+		if !ok {
+			p = &entity.MovieDuplicatePair{A: a, B: b}
+			pairs[[2]string{a, b}] = p
+		}
+		return p
+	}
+
+	maxPairs := dedup.MaxPairs
+	if maxPairs <= 0 {
+		maxPairs = 10000
+	}
+
+	// readPairs adds the pairs a query returns as (a, b, score) rows through
+	// set and reports whether it hit maxPairs.
+	readPairs := func(set func(p *entity.MovieDuplicatePair, score float64), qeury string, args ...interface{}) (bool, error) {
+		rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+		if err != nil {
+			return false, err
+		}
+		defer rows.Close()
+
+		n := 0
+		for rows.Next() {
+			var (
+				a, b  string
+				score float64
+			)
+			if err = rows.Scan(&a, &b, &score); err != nil {
+				return false, err
+			}
+			set(pair(a, b), score)
+			n++
+		}
+
+		return n >= maxPairs, rows.Err()
+	}
+
+	if req.Rescan && dedup.VectorSimilarity > 0 {
+		if err = r.rescanDuplicates(ctx); err != nil {
+			return response, err
+		}
+	}
+
+	if dedup.NameSimilarity > 0 {
+		var scores, filters []string
+		for _, column := range dedupNameColumns {
+			scores = append(scores, fmt.Sprintf("CASE WHEN a.%[1]s <> '' THEN similarity(a.%[1]s, b.%[1]s) ELSE 0 END", column))
+			filters = append(filters, fmt.Sprintf("(a.%[1]s <> '' AND a.%[1]s %% b.%[1]s)", column))
+		}
+
+		truncated, err := readPairs(func(p *entity.MovieDuplicatePair, score float64) { p.NameSimilarity = score },
+			`SELECT a, b, score FROM (
+				SELECT a.id::text AS a, b.id::text AS b, GREATEST(`+strings.Join(scores, ", ")+`)::float8 AS score
+				FROM movies a
+				JOIN movies b ON b.tenant_id = a.tenant_id AND b.id > a.id AND b.deleted_at IS NULL
+					AND (`+strings.Join(filters, " OR ")+`)
+				WHERE a.tenant_id = $1 AND a.deleted_at IS NULL
+			) pairs WHERE score >= $2
+			ORDER BY score DESC, a, b
+			LIMIT $3`, tenantID, dedup.NameSimilarity, maxPairs)
+		if err != nil {
+			return response, err
+		}
+		response.Truncated = response.Truncated || truncated
+	}
+
+	if dedup.VectorSimilarity > 0 {
+		// Stale flags of movies waiting for a new embedding are left out.
+		truncated, err := readPairs(func(p *entity.MovieDuplicatePair, score float64) { p.VectorSimilarity = score },
+			`SELECT d.movie_a::text, d.movie_b::text, d.similarity
+			FROM movie_duplicates d
+			JOIN movies a ON a.id = d.movie_a AND a.deleted_at IS NULL AND a.embedding_status = $2
+			JOIN movies b ON b.id = d.movie_b AND b.deleted_at IS NULL AND b.embedding_status = $2
+			WHERE d.tenant_id = $1 AND d.similarity >= $3
+			ORDER BY d.similarity DESC, d.movie_a, d.movie_b
+			LIMIT $4`, tenantID, entity.EmbeddingReady, dedup.VectorSimilarity, maxPairs)
+		if err != nil {
+			return response, err
+		}
+		response.Truncated = response.Truncated || truncated
+	}
+
+	if len(pairs) == 0 {
+		return response, nil
+	}
+
+	// Connect the pairs into clusters.
+	parent := map[string]string{}
+	var find func(id string) string
+	find = func(id string) string {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		parent[id] = id
+		return id
+	}
+	for key := range pairs {
+		parent[find(key[0])] = find(key[1])
+	}
+
+	members := map[string][]string{}
+	groups := map[string]*entity.MovieDuplicateCluster{}
+	for key, p := range pairs {
+		root := find(key[0])
+		if groups[root] == nil {
+			groups[root] = &entity.MovieDuplicateCluster{}
+		}
+		groups[root].Pairs = append(groups[root].Pairs, *p)
+	}
+	for id := range parent {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+
+	clusters := make([]entity.MovieDuplicateCluster, 0, len(groups))
+	ids := make([]string, 0, len(parent))
+	for root, cluster := range groups {
+		sort.Slice(cluster.Pairs, func(i, j int) bool {
+			si, sj := pairScore(cluster.Pairs[i]), pairScore(cluster.Pairs[j])
+			if si != sj {
+				return si > sj
+			}
+			return cluster.Pairs[i].A+cluster.Pairs[i].B < cluster.Pairs[j].A+cluster.Pairs[j].B
+		})
+		for _, id := range members[root] {
+			cluster.Movies = append(cluster.Movies, entity.MovieDuplicate{ID: id})
+		}
+		clusters = append(clusters, *cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		si, sj := pairScore(clusters[i].Pairs[0]), pairScore(clusters[j].Pairs[0])
+		if si != sj {
+			return si > sj
+		}
+		return clusters[i].Pairs[0].A < clusters[j].Pairs[0].A
+	})
+
+	response.Count = len(clusters)
+	if len(clusters) > req.Limit {
+		clusters = clusters[:req.Limit]
+	}
+	for _, cluster := range clusters {
+		for _, movie := range cluster.Movies {
+			ids = append(ids, movie.ID)
+		}
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select("id, slug, name_uz, name_ru, name_en, release_year").
+		From("movies").
+		Where(scope).
+		Where("id::text = ANY(?)", ids).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	details := map[string]entity.MovieDuplicate{}
+	for rows.Next() {
+		var item entity.MovieDuplicate
+		if err = rows.Scan(&item.ID, &item.Slug, &item.NameUz, &item.NameRu, &item.NameEn, &item.ReleaseYear); err != nil {
+			return response, err
+		}
+		details[item.ID] = item
+	}
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	for i := range clusters {
+		for j, movie := range clusters[i].Movies {
+			if item, ok := details[movie.ID]; ok {
+				clusters[i].Movies[j] = item
+			}
+		}
+		sort.Slice(clusters[i].Movies, func(a, b int) bool {
+			return clusters[i].Movies[a].ID < clusters[i].Movies[b].ID
+		})
+	}
+	response.Clusters = clusters
+
+	return response, nil
+}
+
+// pairScore is the higher of the similarities of p.
+func pairScore(p entity.MovieDuplicatePair) float64 {
+	return max(p.NameSimilarity, p.VectorSimilarity)
+}
+
+// Merge folds req.Duplicates into the movie req.ID and moves them to the
+// trash. The movie keeps its content: its empty names and fields are filled
+// from the duplicates in the given order, their other names and aliases
+// become aliases, their genres and cast are added, and their slugs redirect
+// to it. When it has no external id it takes the first one of the
+// duplicates, so syncing that catalog updates the merged movie.
+func (r *MovieRepo) Merge(ctx context.Context, req entity.MovieMergeRequest) (entity.Movie, error) {
+	if len(req.Duplicates) == 0 || len(req.Duplicates) > maxMergeDuplicates {
+		return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"merge requires 1 to %d duplicates", maxMergeDuplicates)
+	}
+
+	seen := map[string]bool{req.ID: true}
+	for _, id := range req.Duplicates {
+		if seen[id] {
+			return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"movie %s is listed twice or is the merged movie", id)
+		}
+		seen[id] = true
+	}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	previous, err := r.lockMovie(ctx, tx, req.ID, scope, notDeleted)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = checkVersion(req.Version, previous.Version); err != nil {
+		return entity.Movie{}, err
+	}
+
+	duplicates := make([]entity.Movie, len(req.Duplicates))
+	for i, id := range req.Duplicates {
+		duplicates[i], err = r.lockMovie(ctx, tx, id, scope, notDeleted)
+		if err == pgx.ErrNoRows {
+			return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"duplicate %s does not exist", id)
+		}
+		if err != nil {
+			return entity.Movie{}, err
+		}
+	}
+
+	movies := append([]entity.Movie{previous}, duplicates...)
+	if err = r.loadRelations(ctx, movies); err != nil {
+		return entity.Movie{}, err
+	}
+	previous, duplicates = movies[0], movies[1:]
+
+	merged := mergeMovies(previous, duplicates)
+
+	var moved *entity.Movie
+	if previous.ExternalID == "" {
+		for i := range duplicates {
+			if duplicates[i].ExternalID != "" {
+				moved = &duplicates[i]
+				break
+			}
+		}
+	}
+
+	reembed, err := r.documentChanged(&previous, &merged)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	tenantID := scope["tenant_id"]
+
+	// Trash the duplicates first, so their external id is free for the movie.
+	for _, duplicate := range duplicates {
+		movie := duplicate
+
+		var deletedAt time.Time
+
+		err = tx.QueryRow(ctx, `UPDATE movies SET deleted_at = now(), version = version + 1,
+				external_source = CASE WHEN $2 THEN NULL ELSE external_source END,
+				external_id = CASE WHEN $2 THEN NULL ELSE external_id END
+			WHERE id = $1 RETURNING deleted_at, version`, duplicate.ID, moved != nil && moved.ID == duplicate.ID).
+			Scan(&deletedAt, &movie.Version)
+		if err != nil {
+			return entity.Movie{}, err
+		}
+
+		movie.DeletedAt = deletedAt.Format(time.RFC3339)
+		if moved != nil && moved.ID == duplicate.ID {
+			movie.ExternalSource, movie.ExternalID = "", ""
+		}
+
+		if err = r.recordRevision(ctx, tx, revisionDelete, &duplicate, movie); err != nil {
+			return entity.Movie{}, err
+		}
+
+		// Requests for the slugs of the duplicate reach the merged movie.
+		_, err = tx.Exec(ctx, `INSERT INTO movie_slug_history (tenant_id, slug, movie_id) VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, slug) DO UPDATE SET movie_id = EXCLUDED.movie_id, created_at = now()`,
+			tenantID, duplicate.Slug, req.ID)
+		if err != nil {
+			return entity.Movie{}, err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE movie_slug_history SET movie_id = $1 WHERE tenant_id = $2 AND movie_id = $3`,
+			req.ID, tenantID, duplicate.ID)
+		if err != nil {
+			return entity.Movie{}, err
+		}
+	}
+
+	if moved != nil {
+		_, err = tx.Exec(ctx, `UPDATE movies SET external_source = $1, external_id = $2 WHERE id = $3`,
+			moved.ExternalSource, moved.ExternalID, req.ID)
+		if err != nil {
+			return entity.Movie{}, err
+		}
+		previous.ExternalSource, previous.ExternalID = moved.ExternalSource, moved.ExternalID
+	}
+
+	if merged, err = r.save(ctx, tx, merged, &previous, revisionMerge, reembed); err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Movie{}, err
+	}
+
+	return merged, nil
+}
+
+// mergeMovies returns movie with the content of duplicates folded in, as
+// described by Merge.
+func mergeMovies(movie entity.Movie, duplicates []entity.Movie) entity.Movie {
+	merged := movie
+	merged.Aliases = append([]string{}, movie.Aliases...)
+	merged.Genres = append([]entity.Genre{}, movie.Genres...)
+	merged.Cast = append([]entity.MovieCredit{}, movie.Cast...)
+
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	for _, duplicate := range duplicates {
+		fill(&merged.NameUz, duplicate.NameUz)
+		fill(&merged.NameEn, duplicate.NameEn)
+		fill(&merged.NameRu, duplicate.NameRu)
+		fill(&merged.DescriptionUz, duplicate.DescriptionUz)
+		fill(&merged.DescriptionEn, duplicate.DescriptionEn)
+		fill(&merged.DescriptionRu, duplicate.DescriptionRu)
+		fill(&merged.Country, duplicate.Country)
+		fill(&merged.PosterURL, duplicate.PosterURL)
+		if merged.ReleaseYear == 0 {
+			merged.ReleaseYear = duplicate.ReleaseYear
+		}
+		if merged.RuntimeMinutes == 0 {
+			merged.RuntimeMinutes = duplicate.RuntimeMinutes
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range append([]string{merged.NameUz, merged.NameEn, merged.NameRu}, merged.Aliases...) {
+		known[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	genres := map[string]bool{}
+	for _, genre := range merged.Genres {
+		genres[genre.ID] = true
+	}
+
+	credits := map[[2]string]bool{}
+	for _, credit := range merged.Cast {
+		credits[[2]string{credit.PersonID, credit.Role}] = true
+	}
+
+	for _, duplicate := range duplicates {
+		for _, name := range append([]string{duplicate.NameUz, duplicate.NameEn, duplicate.NameRu}, duplicate.Aliases...) {
+			name = strings.TrimSpace(name)
+			if key := strings.ToLower(name); name != "" && !known[key] {
+				known[key] = true
+				merged.Aliases = append(merged.Aliases, name)
+			}
+		}
+
+		for _, genre := range duplicate.Genres {
+			if !genres[genre.ID] {
+				genres[genre.ID] = true
+				merged.Genres = append(merged.Genres, genre)
+			}
+		}
+
+		for _, credit := range duplicate.Cast {
+			key := [2]string{credit.PersonID, credit.Role}
+			if !credits[key] {
+				credits[key] = true
+				credit.Position = len(merged.Cast) + 1
+				merged.Cast = append(merged.Cast, credit)
+			}
+		}
+	}
+
+	sort.Slice(merged.Genres, func(i, j int) bool { return merged.Genres[i].Slug < merged.Genres[j].Slug })
+
+	return merged
+}
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+func TestMergeMovies(t *testing.T) {
+	drama := entity.Genre{ID: "g1", Slug: "drama"}
+	crime := entity.Genre{ID: "g2", Slug: "crime"}
+	action := entity.Genre{ID: "g3", Slug: "action"}
+
+	movie := entity.Movie{
+		ID:          "m1",
+		NameEn:      "The Godfather",
+		NameRu:      "",
+		Aliases:     []string{"Godfather"},
+		ReleaseYear: 1972,
+		Country:     "US",
+		Genres:      []entity.Genre{drama},
+		Cast: []entity.MovieCredit{
+			{PersonID: "p1", Role: "actor", Character: "Vito", Position: 1},
+			{PersonID: "p2", Role: "director", Position: 2},
+		},
+	}
+
+	duplicates := []entity.Movie{
+		{
+			ID:             "m2",
+			NameEn:         "Godfather, The",
+			NameRu:         "Крёстный отец",
+			Aliases:        []string{" the godfather ", "Mario Puzo's The Godfather"},
+			DescriptionEn:  "A crime saga.",
+			ReleaseYear:    1973,
+			RuntimeMinutes: 175,
+			Country:        "IT",
+			Genres:         []entity.Genre{crime, drama},
+			Cast: []entity.MovieCredit{
+				{PersonID: "p1", Role: "actor", Character: "Don Corleone", Position: 1},
+				{PersonID: "p3", Role: "actor", Character: "Michael", Position: 2},
+			},
+		},
+		{
+			ID:             "m3",
+			NameUz:         "Cho'qintirgan ota",
+			NameRu:         "Другой перевод",
+			DescriptionEn:  "Not used, the first duplicate filled it.",
+			RuntimeMinutes: 177,
+			PosterURL:      "https://example.com/poster.jpg",
+			Genres:         []entity.Genre{action},
+			Cast: []entity.MovieCredit{
+				{PersonID: "p2", Role: "writer", Position: 1},
+				{PersonID: "p3", Role: "actor", Position: 1},
+			},
+		},
+	}
+
+	got := mergeMovies(movie, duplicates)
+
+	want := movie
+	want.NameUz = "Cho'qintirgan ota"
+	want.NameRu = "Крёстный отец"
+	want.DescriptionEn = "A crime saga."
+	want.RuntimeMinutes = 175
+	want.PosterURL = "https://example.com/poster.jpg"
+	// Names of the duplicates become aliases unless the movie already has them.
+	want.Aliases = []string{"Godfather", "Godfather, The", "Mario Puzo's The Godfather", "Другой перевод"}
+	want.Genres = []entity.Genre{action, crime, drama}
+	want.Cast = []entity.MovieCredit{
+		{PersonID: "p1", Role: "actor", Character: "Vito", Position: 1},
+		{PersonID: "p2", Role: "director", Position: 2},
+		{PersonID: "p3", Role: "actor", Character: "Michael", Position: 3},
+		{PersonID: "p2", Role: "writer", Position: 4},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeMovies() = %+v\nwant %+v", got, want)
+	}
+
+	if len(movie.Aliases) != 1 || len(movie.Genres) != 1 || len(movie.Cast) != 2 || movie.NameRu != "" {
+		t.Errorf("mergeMovies() changed its input: %+v", movie)
+	}
+}
+
+func TestMergeMoviesWithoutDuplicates(t *testing.T) {
+	movie := entity.Movie{
+		ID:      "m1",
+		NameEn:  "Heat",
+		Aliases: []string{},
+		Genres:  []entity.Genre{{ID: "g2", Slug: "drama"}, {ID: "g1", Slug: "crime"}},
+		Cast:    []entity.MovieCredit{},
+	}
+
+	want := movie
+	want.Genres = []entity.Genre{{ID: "g1", Slug: "crime"}, {ID: "g2", Slug: "drama"}}
+
+	if got := mergeMovies(movie, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeMovies() = %+v, want %+v", got, want)
+	}
+}
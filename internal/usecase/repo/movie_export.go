@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/progress"
+	"github.com/abdulazizax/ai-embedding/pkg/records"
+)
+
+// exportBatch is the number of movies Export reads per query.
+const exportBatch = 500
+
+// movieExportColumns are the columns Export writes. Import reads the movie
+// fields, genres and embedding columns and ignores the others.
+var movieExportColumns = []records.Column{
+	{Name: "id", Type: records.TypeString},
+	{Name: "external_source", Type: records.TypeString},
+	{Name: "external_id", Type: records.TypeString},
+	{Name: "slug", Type: records.TypeString},
+	{Name: "name_uz", Type: records.TypeString},
+	{Name: "name_en", Type: records.TypeString},
+	{Name: "name_ru", Type: records.TypeString},
+	{Name: "aliases", Type: records.TypeStrings},
+	{Name: "description_uz", Type: records.TypeString},
+	{Name: "description_en", Type: records.TypeString},
+	{Name: "description_ru", Type: records.TypeString},
+	{Name: "release_year", Type: records.TypeInt},
+	{Name: "runtime_minutes", Type: records.TypeInt},
+	{Name: "country", Type: records.TypeString},
+	{Name: "poster_url", Type: records.TypeString},
+	{Name: kindGenres, Type: records.TypeStrings},
+	{Name: "created_at", Type: records.TypeString},
+	{Name: "updated_at", Type: records.TypeString},
+	{Name: "embedding_status", Type: records.TypeString},
+	{Name: kindEmbedding, Type: records.TypeFloats},
+	{Name: kindEmbeddingModel, Type: records.TypeString},
+	{Name: kindEmbeddingHash, Type: records.TypeString},
+}
+
+// Export writes the movies of the tenant outside the trash to req.Writer in
+// id order, with genres as slugs and the embedding together with the model
+// and template hash it was made with. Embeddings that are not ready are
+// written as null, since they do not match the content of the movie.
+// Movies are read in batches, so one changed during the export is written
+// as it was when its batch was read. Nothing is written when the first batch
+// cannot be read. It returns the number of movies written.
+//
+// Importing the output with map[external_id]=id recreates the catalog,
+// given its genres exist, without calling the embedder.
+func (r *MovieRepo) Export(ctx context.Context, req entity.MovieExportRequest) (int, error) {
+	var total int
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return total, err
+	}
+
+	writer, err := records.NewWriter(req.Writer, req.Format, movieExportColumns)
+	if err != nil {
+		return total, fmt.Errorf(config.ErrorBadRequest+"%v", err)
+	}
+
+	var last string
+	for {
+		builder := r.pg.Builder.
+			Select(movieColumns).
+			Column("CASE WHEN embedding_status = ? THEN embedding::text END", entity.EmbeddingReady).
+			Column("embedding_template_hash").
+			From("movies").
+			Where(scope).
+			Where(notDeleted).
+			OrderBy("id").
+			Limit(exportBatch)
+		if last != "" {
+			builder = builder.Where("id > ?", last)
+		}
+
+		qeury, args, err := builder.ToSql()
+		if err != nil {
+			return total, err
+		}
+
+		rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+		if err != nil {
+			return total, err
+		}
+
+		var (
+			movies     []entity.Movie
+			embeddings []*string
+			hashes     []string
+		)
+		for rows.Next() {
+			var (
+				movie     entity.Movie
+				embedding *string
+				hash      string
+			)
+			if err = scanMovie(rows, &movie, &embedding, &hash); err != nil {
+				rows.Close()
+				return total, err
+			}
+			movies = append(movies, movie)
+			embeddings = append(embeddings, embedding)
+			hashes = append(hashes, hash)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return total, err
+		}
+
+		if err = r.loadRelations(ctx, movies); err != nil {
+			return total, err
+		}
+
+		for i, m := range movies {
+			var vector []float32
+			if embeddings[i] != nil {
+				if vector, err = parseVectorLiteral(*embeddings[i]); err != nil {
+					return total, fmt.Errorf("MovieRepo - Export - movie %s: %w", m.ID, err)
+				}
+			}
+
+			model := ""
+			if vector != nil {
+				model = r.config.OpenAI.EmbeddingModel
+			}
+
+			genres := make([]string, len(m.Genres))
+			for j, genre := range m.Genres {
+				genres[j] = genre.Slug
+			}
+
+			err = writer.Write([]interface{}{
+				m.ID, m.ExternalSource, m.ExternalID, m.Slug, m.NameUz, m.NameEn, m.NameRu, m.Aliases,
+				m.DescriptionUz, m.DescriptionEn, m.DescriptionRu, m.ReleaseYear, m.RuntimeMinutes,
+				m.Country, m.PosterURL, genres, m.CreatedAt, m.UpdatedAt,
+				m.EmbeddingStatus, vector, model, hashes[i],
+			})
+			if err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		progress.Report(ctx, total, 0)
+
+		if len(movies) < exportBatch {
+			return total, writer.Close()
+		}
+		last = movies[len(movies)-1].ID
+	}
+}
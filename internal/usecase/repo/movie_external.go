@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// externalKeyConstraint is the unique index on the external id of a movie.
+const externalKeyConstraint = "movies_tenant_id_external_key"
+
+// UpsertExternal creates or replaces the movie with req.ExternalSource and
+// req.ExternalID, so a catalog can be synced without tracking our ids. A
+// movie in the trash is restored. The movie is only embedded again when its
+// document changed, and a movie that would not change at all is returned
+// as stored, without a new version. req.Version, when set, must match the
+// stored version; a movie that does not exist yet has none. It reports
+// whether the movie was created.
+func (r *MovieRepo) UpsertExternal(ctx context.Context, req entity.Movie) (entity.Movie, bool, error) {
+	req.ExternalSource, req.ExternalID = strings.TrimSpace(req.ExternalSource), strings.TrimSpace(req.ExternalID)
+	if req.ExternalSource == "" || utf8.RuneCountInString(req.ExternalSource) > 64 {
+		return entity.Movie{}, false, fmt.Errorf(config.ErrorBadRequest + "source must have 1 to 64 characters")
+	}
+	if req.ExternalID == "" || utf8.RuneCountInString(req.ExternalID) > 128 {
+		return entity.Movie{}, false, fmt.Errorf(config.ErrorBadRequest + "external id must have 1 to 128 characters")
+	}
+
+	req.Aliases = cleanPhrases(req.Aliases)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	if err = r.resolveRelations(ctx, &req); err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	movie, created, err := r.upsertExternal(ctx, req, scope)
+
+	// A concurrent request created the movie first; update it instead.
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == externalKeyConstraint {
+		movie, created, err = r.upsertExternal(ctx, req, scope)
+	}
+
+	return movie, created, err
+}
+
+func (r *MovieRepo) upsertExternal(ctx context.Context, req entity.Movie, scope squirrel.Eq) (entity.Movie, bool, error) {
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	qeury, args, err := r.pg.Builder.Select(movieColumns).From("movies").
+		Where(scope).
+		Where("external_source = ? AND external_id = ?", req.ExternalSource, req.ExternalID).
+		Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	var previous entity.Movie
+	err = scanMovie(tx.QueryRow(ctx, qeury, args...), &previous)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err = checkVersion(req.Version, 0); err != nil {
+			return entity.Movie{}, false, err
+		}
+
+		// Movies in the trash still count, as for Create.
+		if err = checkQuota(ctx, tx, "movies", "max_movies"); err != nil {
+			return entity.Movie{}, false, err
+		}
+
+		req.ID = uuid.NewString()
+		if req, err = r.insert(ctx, tx, req); err != nil {
+			return entity.Movie{}, false, err
+		}
+
+		return req, true, tx.Commit(ctx)
+	}
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	movies := []entity.Movie{previous}
+	if err = r.loadRelations(ctx, movies); err != nil {
+		return entity.Movie{}, false, err
+	}
+	previous = movies[0]
+
+	if err = checkVersion(req.Version, previous.Version); err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	req.ID = previous.ID
+
+	// Compare with the slug assignSlug would keep, so unchanged movies are
+	// recognised without writing them.
+	candidate := req
+	if candidate.Slug == "" || candidate.Slug == previous.Slug {
+		candidate.Slug = previous.Slug
+	}
+	diff, err := diffMovies(&previous, candidate)
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+	if len(diff) == 0 {
+		return previous, false, nil
+	}
+
+	reembed, err := r.documentChanged(&previous, &req)
+	if err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	if req, err = r.save(ctx, tx, req, &previous, revisionUpdate, reembed); err != nil {
+		return entity.Movie{}, false, err
+	}
+
+	return req, false, tx.Commit(ctx)
+}
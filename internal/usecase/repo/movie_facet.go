@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+)
+
+// yearBucket is the width of the release year facet buckets.
+const yearBucket = 10
+
+// movieFacetQueries count one facet each over the matched CTE. Every query
+// yields facet, value, label and count, so they can be combined with UNION ALL.
+var movieFacetQueries = map[string]string{
+	entity.FacetGenre: `SELECT 'genre', g.slug, g.name_en, COUNT(*)
+		FROM matched m
+		JOIN movie_genres mg ON mg.movie_id = m.id
+		JOIN genres g ON g.id = mg.genre_id
+		GROUP BY g.slug, g.name_en`,
+	entity.FacetYear: fmt.Sprintf(`SELECT 'year', y.value, y.label, COUNT(*)
+		FROM matched m
+		CROSS JOIN LATERAL (
+			SELECT
+				CASE WHEN m.release_year > 0 THEN (m.release_year / %[1]d * %[1]d)::text ELSE 'unknown' END AS value,
+				CASE WHEN m.release_year > 0 THEN (m.release_year / %[1]d * %[1]d)::text || '-' || (m.release_year / %[1]d * %[1]d + %[1]d - 1)::text ELSE '' END AS label
+		) y
+		GROUP BY y.value, y.label`, yearBucket),
+	entity.FacetLanguage: `SELECT 'language', l.lang, '', COUNT(*)
+		FROM matched m
+		CROSS JOIN LATERAL (VALUES ('uz', m.name_uz), ('en', m.name_en), ('ru', m.name_ru)) l(lang, name)
+		WHERE l.name <> ''
+		GROUP BY l.lang`,
+}
+
+// facets counts the requested facets over the movies matching where with a
+// single query.
+func (r *MovieRepo) facets(ctx context.Context, names []string, where squirrel.Sqlizer) (*entity.MovieFacets, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var (
+		parts     []string
+		requested = map[string]bool{}
+	)
+
+	for _, name := range names {
+		query, ok := movieFacetQueries[name]
+		if !ok {
+			return nil, fmt.Errorf(config.ErrorBadRequest+"unknown facet %q", name)
+		}
+
+		if !requested[name] {
+			requested[name] = true
+			parts = append(parts, query)
+		}
+	}
+
+	matched, args, err := r.pg.Builder.
+		Select("id", "release_year", "name_uz", "name_en", "name_ru").
+		From("movies").
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	// Years are listed by decade, everything else by count.
+	qeury := `WITH matched AS (` + matched + `)
+	SELECT * FROM (` + strings.Join(parts, "\n\t\tUNION ALL\n\t\t") + `) facets (facet, value, label, count)
+	ORDER BY facet, CASE WHEN facet = 'year' THEN value END, count DESC, value`
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := &entity.MovieFacets{}
+	for rows.Next() {
+		var (
+			facet string
+			item  entity.FacetCount
+		)
+		if err = rows.Scan(&facet, &item.Value, &item.Label, &item.Count); err != nil {
+			return nil, err
+		}
+
+		switch facet {
+		case entity.FacetGenre:
+			facets.Genres = append(facets.Genres, item)
+		case entity.FacetYear:
+			facets.Years = append(facets.Years, item)
+		case entity.FacetLanguage:
+			facets.Languages = append(facets.Languages, item)
+		}
+	}
+
+	return facets, rows.Err()
+}
@@ -0,0 +1,857 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/progress"
+	"github.com/abdulazizax/ai-embedding/pkg/records"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Kinds of import fields that are not movie columns.
+const (
+	kindGenres         = "genres"
+	kindExternalID     = "external_id"
+	kindEmbedding      = "embedding"
+	kindEmbeddingModel = "embedding_model"
+	kindEmbeddingHash  = "embedding_template_hash"
+)
+
+// importMaxLength holds the length limits of the varchar columns an import
+// writes, so an oversized value fails its line instead of its batch.
+var importMaxLength = map[string]int{
+	"name_uz":     256,
+	"name_en":     256,
+	"name_ru":     256,
+	"country":     64,
+	"external_id": 128,
+}
+
+// importKind returns the kind of value an import field takes. Imports can set
+// every column a bulk update can, plus genres, the external id and an
+// embedding with the model and template hash it was made with.
+func importKind(name string) (string, bool) {
+	switch name {
+	case kindGenres, kindExternalID, kindEmbedding, kindEmbeddingModel, kindEmbeddingHash:
+		return name, true
+	}
+
+	kind, ok := movieUpdatableColumns[name]
+	return kind, ok
+}
+
+// importColumns lists the staging columns of an import batch in COPY order.
+var importColumns = []string{
+	"id", "version", "slug", "name_uz", "name_en", "name_ru", "aliases",
+	"description_uz", "description_en", "description_ru",
+	"release_year", "runtime_minutes", "country", "poster_url", "external_id",
+	"embedding", "embedding_template_hash",
+}
+
+// importLine is a parsed line waiting in a batch.
+type importLine struct {
+	report  int // index in MovieImportReport.Lines
+	values  map[string]interface{}
+	movie   entity.Movie
+	before  *entity.Movie // stored state, nil for a new movie
+	reembed bool
+	restore []float32 // embedding stored instead of queueing the movie
+}
+
+// Import creates or updates movies from a CSV, JSON Lines, JSON array or
+// Arrow upload, matching stored movies by source and external id. Lines are
+// processed in batches: each batch is written in one transaction through
+// COPY and embedded afterwards by the embedding workers. Lines carrying an
+// embedding made by the configured model, as Export writes them, have it
+// stored instead, so restoring an export does not call the embedder; the
+// template hash comes along, and ReembedStale renews embeddings made with
+// another template. Movies in the trash are restored when their line
+// arrives again. Lines that fail validation are reported and skipped; an
+// error writing a batch stops the import and is reported with the lines of
+// that batch.
+func (r *MovieRepo) Import(ctx context.Context, req entity.MovieImportRequest) (entity.MovieImportReport, error) {
+	report := entity.MovieImportReport{Lines: []entity.MovieImportLine{}}
+
+	tenantID := tenant.ID(ctx)
+	if tenantID == "" {
+		return report, fmt.Errorf("MovieRepo - Import - tenant is not resolved")
+	}
+
+	source := strings.TrimSpace(req.Source)
+	if source == "" || utf8.RuneCountInString(source) > 64 {
+		return report, fmt.Errorf(config.ErrorBadRequest + "source must have 1 to 64 characters")
+	}
+
+	for field, column := range req.Mapping {
+		if _, ok := importKind(field); !ok {
+			return report, fmt.Errorf(config.ErrorBadRequest+"field %q cannot be imported", field)
+		}
+		if column == "" {
+			return report, fmt.Errorf(config.ErrorBadRequest+"mapping of %q names no column", field)
+		}
+	}
+
+	reader, err := records.NewReader(req.Body, req.Format)
+	if err != nil {
+		return report, fmt.Errorf(config.ErrorBadRequest+"%v", err)
+	}
+
+	genres, err := r.importGenres(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	batchSize := r.config.Import.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var (
+		batch []*importLine
+		seen  = map[string]int{}
+	)
+
+	fail := func(line int, externalID, message string) {
+		report.Lines = append(report.Lines, entity.MovieImportLine{
+			Line:       line,
+			ExternalID: externalID,
+			Status:     entity.ImportFailed,
+			Error:      message,
+		})
+	}
+
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+
+		err := r.importBatch(ctx, tenantID, source, batch, &report)
+		if err != nil {
+			r.logger.Error(err, "MovieRepo - Import - importBatch")
+			report.Error = "import stopped: " + err.Error()
+			for _, line := range batch {
+				if report.Lines[line.report].Status == "" {
+					report.Lines[line.report].Status = entity.ImportFailed
+					report.Lines[line.report].Error = "not imported: " + err.Error()
+				}
+			}
+		}
+
+		batch = batch[:0]
+		progress.Report(ctx, len(report.Lines), 0)
+		return err == nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var lineErr *records.LineError
+		if errors.As(err, &lineErr) {
+			fail(lineErr.Line, "", lineErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			report.Error = "import stopped: " + err.Error()
+			break
+		}
+
+		values, err := importValues(record.Fields, req.Mapping, genres)
+		externalID, _ := values[kindExternalID].(string)
+		if err != nil {
+			fail(record.Line, externalID, err.Error())
+			continue
+		}
+
+		if first, ok := seen[externalID]; ok {
+			fail(record.Line, externalID, fmt.Sprintf("external_id already used on line %d", first))
+			continue
+		}
+		seen[externalID] = record.Line
+
+		report.Lines = append(report.Lines, entity.MovieImportLine{Line: record.Line, ExternalID: externalID})
+		batch = append(batch, &importLine{report: len(report.Lines) - 1, values: values})
+
+		if len(batch) >= batchSize && !flush() {
+			break
+		}
+	}
+
+	if report.Error == "" {
+		flush()
+	}
+
+	for _, line := range report.Lines {
+		switch line.Status {
+		case entity.ImportCreated:
+			report.Created++
+		case entity.ImportUpdated:
+			report.Updated++
+		case entity.ImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+		if line.Restored {
+			report.Restored++
+		}
+	}
+
+	return report, nil
+}
+
+// importGenres maps the ids and slugs of the genres of the tenant to the
+// genres, with the fields loadRelations reads.
+func (r *MovieRepo) importGenres(ctx context.Context) (map[string]entity.Genre, error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, slug, name_uz, name_en, name_ru`).
+		From("genres").
+		Where(scope).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := map[string]entity.Genre{}
+	for rows.Next() {
+		var genre entity.Genre
+		if err = rows.Scan(&genre.ID, &genre.Slug, &genre.NameUz, &genre.NameEn, &genre.NameRu); err != nil {
+			return nil, err
+		}
+
+		genres[genre.ID] = genre
+		genres[genre.Slug] = genre
+	}
+
+	return genres, rows.Err()
+}
+
+// importValues reads the mapped fields present in a record and converts
+// them to column values. Fields missing from the record are left out, so
+// updates keep their stored values.
+func importValues(fields map[string]interface{}, mapping map[string]string, genres map[string]entity.Genre) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	column := func(field string) string {
+		if c, ok := mapping[field]; ok {
+			return c
+		}
+		return field
+	}
+
+	// The external id is read first so failures can be reported with it.
+	raw, ok := fields[column(kindExternalID)]
+	if !ok {
+		return values, fmt.Errorf("column %q is missing", column(kindExternalID))
+	}
+	externalID, err := importText(kindExternalID, raw)
+	if err != nil {
+		return values, err
+	}
+	externalID = strings.TrimSpace(externalID)
+	values[kindExternalID] = externalID
+	if externalID == "" {
+		return values, fmt.Errorf("external_id is required")
+	}
+	if utf8.RuneCountInString(externalID) > importMaxLength[kindExternalID] {
+		return values, fmt.Errorf("external_id is longer than %d characters", importMaxLength[kindExternalID])
+	}
+
+	for field, kind := range movieUpdatableColumns {
+		raw, ok := fields[column(field)]
+		if !ok {
+			continue
+		}
+
+		var value interface{}
+		switch kind {
+		case kindText:
+			text, err := importText(field, raw)
+			if err != nil {
+				return values, err
+			}
+			if max, ok := importMaxLength[field]; ok && utf8.RuneCountInString(text) > max {
+				return values, fmt.Errorf("%s is longer than %d characters", field, max)
+			}
+			value = text
+		case kindInt:
+			number, err := importInt(field, raw)
+			if err != nil {
+				return values, err
+			}
+			value = number
+		case kindTextArray:
+			list, err := importList(field, raw)
+			if err != nil {
+				return values, err
+			}
+			value = cleanPhrases(list)
+		}
+
+		values[field] = value
+	}
+
+	if raw, ok := fields[column(kindGenres)]; ok {
+		keys, err := importList(kindGenres, raw)
+		if err != nil {
+			return values, err
+		}
+
+		list := make([]entity.Genre, 0, len(keys))
+		added := map[string]bool{}
+		for _, key := range keys {
+			genre, ok := genres[strings.TrimSpace(key)]
+			if !ok {
+				return values, fmt.Errorf("unknown genre %q", key)
+			}
+			if !added[genre.ID] {
+				added[genre.ID] = true
+				list = append(list, genre)
+			}
+		}
+
+		// Stored genres are read in slug order.
+		sort.Slice(list, func(i, j int) bool { return list[i].Slug < list[j].Slug })
+		values[kindGenres] = list
+	}
+
+	if raw, ok := fields[column(kindEmbedding)]; ok {
+		vector, err := importVector(raw)
+		if err != nil {
+			return values, err
+		}
+		if vector != nil {
+			values[kindEmbedding] = vector
+		}
+	}
+
+	for _, field := range []string{kindEmbeddingModel, kindEmbeddingHash} {
+		if raw, ok := fields[column(field)]; ok {
+			text, err := importText(field, raw)
+			if err != nil {
+				return values, err
+			}
+			values[field] = strings.TrimSpace(text)
+		}
+	}
+
+	return values, nil
+}
+
+// importVector reads an embedding from a "[x,y,...]" string, a JSON array
+// of numbers or an Arrow list of floats. Empty values give a nil vector.
+func importVector(raw interface{}) ([]float32, error) {
+	var vector []float32
+
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		parsed, err := parseVectorLiteral(v)
+		if err != nil {
+			return nil, fmt.Errorf("embedding: %v", err)
+		}
+		vector = parsed
+	case []float32:
+		vector = v
+	case []interface{}:
+		vector = make([]float32, len(v))
+		for i, item := range v {
+			number, ok := item.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("embedding must be an array of numbers")
+			}
+			f, err := strconv.ParseFloat(number.String(), 32)
+			if err != nil {
+				return nil, fmt.Errorf("embedding element %d is not a float", i+1)
+			}
+			vector[i] = float32(f)
+		}
+	default:
+		return nil, fmt.Errorf("embedding must be an array of numbers")
+	}
+
+	for i, f := range vector {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return nil, fmt.Errorf("embedding element %d is not a finite number", i+1)
+		}
+	}
+
+	return vector, nil
+}
+
+// restorableEmbedding returns the embedding of a line when it was made by the
+// configured model and fits the embedding column, or nil when the movie has
+// to be embedded again.
+func (r *MovieRepo) restorableEmbedding(values map[string]interface{}) []float32 {
+	vector, _ := values[kindEmbedding].([]float32)
+	model, _ := values[kindEmbeddingModel].(string)
+
+	if len(vector) != embeddingDimensions || model != r.config.OpenAI.EmbeddingModel {
+		return nil
+	}
+
+	return vector
+}
+
+func importText(field string, raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+
+	return "", fmt.Errorf("%s must be a string", field)
+}
+
+func importInt(field string, raw interface{}) (int, error) {
+	var text string
+	switch v := raw.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		text = strings.TrimSpace(v)
+		if text == "" {
+			return 0, nil
+		}
+	case json.Number:
+		text = v.String()
+	default:
+		return 0, fmt.Errorf("%s must be a non-negative integer", field)
+	}
+
+	number, err := strconv.Atoi(text)
+	if err != nil || number < 0 || number > math.MaxInt32 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", field)
+	}
+
+	return number, nil
+}
+
+// importList reads a JSON array of strings or a "|" separated string.
+func importList(field string, raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return []string{}, nil
+	case string:
+		list := []string{}
+		for _, item := range strings.Split(v, "|") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of strings", field)
+			}
+			list = append(list, text)
+		}
+		return list, nil
+	}
+
+	return nil, fmt.Errorf("%s must be an array of strings or a | separated string", field)
+}
+
+// applyImportValues sets the imported values on movie.
+func applyImportValues(movie *entity.Movie, values map[string]interface{}) {
+	text := func(field string, target *string) {
+		if v, ok := values[field].(string); ok {
+			*target = v
+		}
+	}
+	number := func(field string, target *int) {
+		if v, ok := values[field].(int); ok {
+			*target = v
+		}
+	}
+
+	text("name_uz", &movie.NameUz)
+	text("name_en", &movie.NameEn)
+	text("name_ru", &movie.NameRu)
+	text("description_uz", &movie.DescriptionUz)
+	text("description_en", &movie.DescriptionEn)
+	text("description_ru", &movie.DescriptionRu)
+	text("country", &movie.Country)
+	text("poster_url", &movie.PosterURL)
+	number("release_year", &movie.ReleaseYear)
+	number("runtime_minutes", &movie.RuntimeMinutes)
+
+	if v, ok := values["aliases"].([]string); ok {
+		movie.Aliases = v
+	}
+	if v, ok := values[kindGenres].([]entity.Genre); ok {
+		movie.Genres = v
+	}
+}
+
+// importBatch resolves and writes one batch and queues the movies whose
+// embedding document changed for the embedding workers. Lines it rejects are
+// marked failed in the report; an error means nothing of the batch was written.
+func (r *MovieRepo) importBatch(ctx context.Context, tenantID, source string, batch []*importLine, report *entity.MovieImportReport) error {
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// Movies in the trash still count, as for Create. The tenant stays
+	// locked until the batch is written.
+	limit, count, err := quotaUsage(ctx, tx, "movies", "max_movies")
+	if err != nil {
+		return err
+	}
+	remaining := -1
+	if limit > 0 {
+		remaining = max(limit-count, 0)
+	}
+
+	externalIDs := make([]string, len(batch))
+	for i, line := range batch {
+		externalIDs[i] = line.values[kindExternalID].(string)
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(movieColumns).
+		From("movies").
+		Where("tenant_id = ? AND external_source = ? AND external_id = ANY(?)", tenantID, source, externalIDs).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stored, err := scanMovies(tx.Query(ctx, qeury, args...))
+	if err != nil {
+		return err
+	}
+
+	if err = r.loadRelations(ctx, stored); err != nil {
+		return err
+	}
+
+	existing := make(map[string]*entity.Movie, len(stored))
+	for i := range stored {
+		existing[stored[i].ExternalID] = &stored[i]
+	}
+
+	var pending []*importLine
+
+	for _, line := range batch {
+		result := &report.Lines[line.report]
+		externalID := line.values[kindExternalID].(string)
+
+		if before, ok := existing[externalID]; ok {
+			line.before = before
+			line.movie = *before
+			line.movie.Aliases = append([]string{}, before.Aliases...)
+			line.movie.DeletedAt = ""
+		} else {
+			line.movie = entity.Movie{
+				ID:             uuid.NewString(),
+				Aliases:        []string{},
+				Genres:         []entity.Genre{},
+				Cast:           []entity.MovieCredit{},
+				ExternalSource: source,
+				ExternalID:     externalID,
+			}
+		}
+		applyImportValues(&line.movie, line.values)
+		result.ID = line.movie.ID
+
+		if line.movie.NameUz == "" && line.movie.NameEn == "" && line.movie.NameRu == "" {
+			result.Status, result.Error = entity.ImportFailed, "at least one name is required"
+			continue
+		}
+
+		if line.before == nil {
+			if remaining == 0 {
+				result.Status, result.Error = entity.ImportFailed, "movie quota exceeded"
+				continue
+			}
+			if remaining > 0 {
+				remaining--
+			}
+			line.reembed = true
+		} else {
+			diff, err := diffMovies(line.before, line.movie)
+			if err != nil {
+				return err
+			}
+			if len(diff) == 0 {
+				result.Status = entity.ImportUnchanged
+				continue
+			}
+
+			line.reembed, err = r.documentChanged(line.before, &line.movie)
+			if err != nil {
+				return err
+			}
+		}
+
+		if line.reembed {
+			line.restore = r.restorableEmbedding(line.values)
+		}
+
+		pending = append(pending, line)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err = r.importSlugs(ctx, tx, tenantID, pending); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE movie_import (
+		id TEXT NOT NULL, version INT NOT NULL, slug TEXT NOT NULL,
+		name_uz TEXT NOT NULL, name_en TEXT NOT NULL, name_ru TEXT NOT NULL, aliases TEXT[] NOT NULL,
+		description_uz TEXT NOT NULL, description_en TEXT NOT NULL, description_ru TEXT NOT NULL,
+		release_year INT NOT NULL, runtime_minutes INT NOT NULL, country TEXT NOT NULL, poster_url TEXT NOT NULL,
+		external_id TEXT NOT NULL, embedding TEXT, embedding_template_hash TEXT NOT NULL
+	) ON COMMIT DROP`)
+	if err != nil {
+		return err
+	}
+
+	// COPY cannot write to movies directly because of its row-level security,
+	// so the batch is copied to a staging table and upserted from there.
+	rows := make([][]interface{}, len(pending))
+	for i, line := range pending {
+		m := line.movie
+		version := 0
+		if line.before != nil {
+			version = line.before.Version
+		}
+
+		var embedding *string
+		hash, _ := line.values[kindEmbeddingHash].(string)
+		if line.restore != nil {
+			literal := formatVectorLiteral(line.restore)
+			embedding = &literal
+		}
+
+		rows[i] = []interface{}{
+			m.ID, version, m.Slug, m.NameUz, m.NameEn, m.NameRu, m.Aliases,
+			m.DescriptionUz, m.DescriptionEn, m.DescriptionRu,
+			m.ReleaseYear, m.RuntimeMinutes, m.Country, m.PosterURL, m.ExternalID,
+			embedding, hash,
+		}
+	}
+
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"movie_import"}, importColumns, pgx.CopyFromRows(rows)); err != nil {
+		return err
+	}
+
+	// Updates only apply when the movie is still at the version read above.
+	upserted, err := tx.Query(ctx, `INSERT INTO movies (id, tenant_id, slug, name_uz, name_en, name_ru, aliases,
+			description_uz, description_en, description_ru, release_year, runtime_minutes, country, poster_url,
+			external_source, external_id)
+		SELECT id::uuid, $1::uuid, slug, name_uz, name_en, name_ru, aliases,
+			description_uz, description_en, description_ru, release_year, runtime_minutes, country, poster_url,
+			$2, external_id
+		FROM movie_import
+		ON CONFLICT (id) DO UPDATE SET
+			slug = EXCLUDED.slug, name_uz = EXCLUDED.name_uz, name_en = EXCLUDED.name_en, name_ru = EXCLUDED.name_ru,
+			aliases = EXCLUDED.aliases, description_uz = EXCLUDED.description_uz, description_en = EXCLUDED.description_en,
+			description_ru = EXCLUDED.description_ru, release_year = EXCLUDED.release_year,
+			runtime_minutes = EXCLUDED.runtime_minutes, country = EXCLUDED.country, poster_url = EXCLUDED.poster_url,
+			deleted_at = NULL, updated_at = now(), version = movies.version + 1
+		WHERE movies.tenant_id = EXCLUDED.tenant_id
+			AND movies.version = (SELECT s.version FROM movie_import s WHERE s.id = EXCLUDED.id::text)
+		RETURNING id::text, created_at, updated_at, version`,
+		tenantID, source)
+	if err != nil {
+		return err
+	}
+
+	type written struct {
+		createdAt, updatedAt time.Time
+		version              int
+	}
+	results := map[string]written{}
+
+	for upserted.Next() {
+		var (
+			id string
+			w  written
+		)
+		if err = upserted.Scan(&id, &w.createdAt, &w.updatedAt, &w.version); err != nil {
+			upserted.Close()
+			return err
+		}
+		results[id] = w
+	}
+	upserted.Close()
+	if err = upserted.Err(); err != nil {
+		return err
+	}
+
+	var (
+		statuses []func()
+		reembed  []string
+		restored []string
+	)
+	for _, line := range pending {
+		result := &report.Lines[line.report]
+
+		w, ok := results[line.movie.ID]
+		if !ok {
+			statuses = append(statuses, func() {
+				result.Status, result.Error = entity.ImportFailed, "movie changed during the import, retry the line"
+			})
+			continue
+		}
+
+		line.movie.CreatedAt = w.createdAt.Format(time.RFC3339)
+		line.movie.UpdatedAt = w.updatedAt.Format(time.RFC3339)
+		line.movie.Version = w.version
+
+		action, status := revisionCreate, entity.ImportCreated
+		if line.before != nil {
+			action, status = revisionUpdate, entity.ImportUpdated
+		}
+
+		if _, ok := line.values[kindGenres]; ok {
+			if err = r.saveRelations(ctx, tx, line.movie); err != nil {
+				return err
+			}
+		}
+
+		if err = r.recordRevision(ctx, tx, action, line.before, line.movie); err != nil {
+			return err
+		}
+
+		restore := line.restore != nil
+		switch {
+		case restore:
+			restored = append(restored, line.movie.ID)
+		case line.reembed:
+			reembed = append(reembed, line.movie.ID)
+		}
+
+		statuses = append(statuses, func() { result.Status, result.Restored = status, restore })
+	}
+
+	if err = queueEmbeddings(ctx, tx, reembed); err != nil {
+		return err
+	}
+
+	if err = restoreEmbeddings(ctx, tx, restored); err != nil {
+		return err
+	}
+	if err = r.flagDuplicates(ctx, tx, restored); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, set := range statuses {
+		set()
+	}
+
+	return nil
+}
+
+// importSlugs assigns slugs to the pending lines of a batch inside tx. Updated
+// movies go through assignSlug; new movies get unique slugs from a single
+// lookup of the taken ones.
+func (r *MovieRepo) importSlugs(ctx context.Context, tx pgx.Tx, tenantID string, pending []*importLine) error {
+	var (
+		bases   []string
+		created []*importLine
+		claimed = map[string]bool{}
+	)
+
+	for _, line := range pending {
+		if line.before == nil {
+			created = append(created, line)
+			bases = append(bases, movieSlugBase(line.movie))
+			continue
+		}
+
+		if err := r.assignSlug(ctx, tx, &line.movie, line.before); err != nil {
+			return err
+		}
+		claimed[line.movie.Slug] = true
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+
+	taken, err := takenSlugs(ctx, tx, tenantID, "", bases...)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range created {
+		s := bases[i]
+		for n := 2; taken[s] || claimed[s]; n++ {
+			s = bases[i] + "-" + strconv.Itoa(n)
+		}
+
+		line.movie.Slug = s
+		claimed[s] = true
+	}
+
+	return nil
+}
+
+// restoreEmbeddings stores the embeddings staged with the given movies of an
+// import batch in tx. Embeddings queued for them earlier are dropped, since
+// the restored ones belong to the imported content.
+func restoreEmbeddings(ctx context.Context, tx pgx.Tx, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `UPDATE movies m SET
+			embedding = s.embedding::vector, embedding_template_hash = s.embedding_template_hash,
+			embedding_status = $2, embedding_error = ''
+		FROM movie_import s
+		WHERE m.id = s.id::uuid AND m.id::text = ANY($1)`, ids, entity.EmbeddingReady)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM embedding_outbox WHERE movie_id::text = ANY($1)`, ids)
+
+	return err
+}
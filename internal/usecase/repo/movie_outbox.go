@@ -0,0 +1,292 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/jackc/pgx/v4"
+)
+
+// outboxLease is how long claimed outbox rows stay invisible to other
+// workers. It only matters when a worker dies while embedding.
+const outboxLease = 5 * time.Minute
+
+// outboxRow is a claimed embedding_outbox row.
+type outboxRow struct {
+	movieID  string
+	token    int64
+	attempts int
+}
+
+// queueEmbeddings marks movies pending and queues them for the embedding
+// workers in tx, so the request is lost only if the write is.
+func queueEmbeddings(ctx context.Context, tx pgx.Tx, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `UPDATE movies SET embedding_status = $2, embedding_error = '' WHERE id = ANY($1)`,
+		ids, entity.EmbeddingPending)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO embedding_outbox (movie_id, tenant_id)
+		SELECT id, tenant_id FROM movies WHERE id = ANY($1)
+		ON CONFLICT (movie_id) DO UPDATE SET
+			token = EXCLUDED.token, attempts = 0, error = '', run_at = now(), locked_until = NULL`, ids)
+
+	return err
+}
+
+// ProcessEmbeddings claims a batch of queued movies, embeds their current
+// documents and stores the vectors, flagging the movies they are near
+// duplicates of. It returns the number of movies claimed, so callers can keep
+// going while the outbox is not drained. When the batch cannot be embedded,
+// its documents are embedded one at a time; the ones that fail then are
+// retried with backoff, and movies that run out of attempts are marked
+// failed and left for ReembedStale.
+func (r *MovieRepo) ProcessEmbeddings(ctx context.Context) (int, error) {
+	batch := r.config.Embedding.ReembedBatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, `UPDATE embedding_outbox SET
+			attempts = attempts + 1,
+			locked_until = now() + $2::bigint * interval '1 millisecond'
+		WHERE movie_id IN (
+			SELECT movie_id FROM embedding_outbox
+			WHERE run_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY run_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING movie_id::text, token, attempts`, batch, outboxLease.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+
+	var claimed []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err = rows.Scan(&row.movieID, &row.token, &row.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		claimed = append(claimed, row)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(claimed) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, len(claimed))
+	for i, row := range claimed {
+		ids[i] = row.movieID
+	}
+
+	qeury, args, err := r.pg.Builder.Select(movieColumns).From("movies").Where("id = ANY(?)", ids).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	movies, err := scanMovies(r.pg.Pool.Query(ctx, qeury, args...))
+	if err != nil {
+		return 0, err
+	}
+
+	if err = r.loadRelations(ctx, movies); err != nil {
+		return 0, err
+	}
+
+	byID := make(map[string]*entity.Movie, len(movies))
+	for i := range movies {
+		byID[movies[i].ID] = &movies[i]
+	}
+
+	var (
+		embedded  []outboxRow
+		documents []string
+	)
+
+	for _, row := range claimed {
+		movie, ok := byID[row.movieID]
+		if !ok {
+			// Purged meanwhile; its outbox row went with it.
+			continue
+		}
+
+		document, err := r.document.Render(movie)
+		if err != nil {
+			// Rendering the same movie again will not succeed either.
+			r.failEmbedding(ctx, row, err, true)
+			continue
+		}
+
+		embedded = append(embedded, row)
+		documents = append(documents, document)
+	}
+
+	vectors, embedErr := r.embedDocuments(ctx, documents)
+	if embedErr != nil && len(embedded) > 1 && ctx.Err() == nil {
+		// One bad document fails the whole request, so the documents are
+		// embedded one at a time and only the ones that fail again count
+		// the attempt.
+		embedded, vectors, embedErr = r.embedEach(ctx, embedded, documents)
+	}
+	if embedErr != nil && len(vectors) == 0 {
+		for _, row := range embedded {
+			if ctx.Err() != nil {
+				r.releaseEmbedding(row)
+			} else {
+				r.failEmbedding(ctx, row, embedErr, false)
+			}
+		}
+		return len(claimed), embedErr
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return len(claimed), err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var stored []string
+	for i, row := range embedded {
+		// A movie queued again since it was claimed keeps its row, and the
+		// vector of its newer content is stored by the next round instead.
+		tag, err := tx.Exec(ctx, `WITH done AS (
+				DELETE FROM embedding_outbox WHERE movie_id = $1 AND token = $2 RETURNING movie_id
+			)
+			UPDATE movies SET embedding = $3, embedding_template_hash = $4, embedding_status = $5, embedding_error = ''
+			WHERE id IN (SELECT movie_id FROM done)`,
+			row.movieID, row.token, formatVectorLiteral(vectors[i]), r.document.Hash(), entity.EmbeddingReady)
+		if err != nil {
+			return len(claimed), err
+		}
+		if tag.RowsAffected() > 0 {
+			stored = append(stored, row.movieID)
+		}
+	}
+
+	if err = r.flagDuplicates(ctx, tx, stored); err != nil {
+		return len(claimed), err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return len(claimed), err
+	}
+
+	return len(claimed), embedErr
+}
+
+// embedEach embeds the documents of the claimed rows one at a time after
+// embedding them together failed. It returns the rows that were embedded
+// with their vectors; the others are failed or, once ctx is done, released,
+// and the last error is returned with them.
+func (r *MovieRepo) embedEach(ctx context.Context, rows []outboxRow, documents []string) ([]outboxRow, [][]float32, error) {
+	var (
+		embedded []outboxRow
+		vectors  [][]float32
+		lastErr  error
+	)
+
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			r.releaseEmbedding(row)
+			lastErr = ctx.Err()
+			continue
+		}
+
+		vector, err := r.embedDocuments(ctx, documents[i:i+1])
+		if err != nil {
+			if ctx.Err() != nil {
+				r.releaseEmbedding(row)
+			} else {
+				r.failEmbedding(ctx, row, err, false)
+			}
+			lastErr = err
+			continue
+		}
+
+		embedded = append(embedded, row)
+		vectors = append(vectors, vector[0])
+	}
+
+	return embedded, vectors, lastErr
+}
+
+// embedDocuments embeds documents in batches of Embedding.ReembedBatch.
+func (r *MovieRepo) embedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	batch := r.config.Embedding.ReembedBatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	vectors := make([][]float32, 0, len(documents))
+	for start := 0; start < len(documents); start += batch {
+		end := start + batch
+		if end > len(documents) {
+			end = len(documents)
+		}
+
+		chunk, err := createEmbeddings(ctx, r.openaiClient, r.config.OpenAI.EmbeddingModel, documents[start:end], embeddingDimensions)
+		if err != nil {
+			return nil, fmt.Errorf("MovieRepo - embedDocuments - %w", err)
+		}
+		vectors = append(vectors, chunk...)
+	}
+
+	return vectors, nil
+}
+
+// failEmbedding schedules another attempt for a claimed movie, or marks it
+// failed when it ran out of attempts or permanent is set. Failed movies get
+// an empty template hash, so ReembedStale tries them again.
+func (r *MovieRepo) failEmbedding(ctx context.Context, row outboxRow, cause error, permanent bool) {
+	message := cause.Error()
+
+	if permanent || row.attempts >= max(r.config.Jobs.MaxAttempts, 1) {
+		_, err := r.pg.Pool.Exec(ctx, `WITH done AS (
+				DELETE FROM embedding_outbox WHERE movie_id = $1 AND token = $2 RETURNING movie_id
+			)
+			UPDATE movies SET embedding_status = $3, embedding_error = $4, embedding_template_hash = ''
+			WHERE id IN (SELECT movie_id FROM done)`,
+			row.movieID, row.token, entity.EmbeddingFailed, message)
+		if err != nil {
+			r.logger.Error(fmt.Errorf("MovieRepo - failEmbedding - %w", err))
+		}
+		return
+	}
+
+	// Backoff doubles from Jobs.BackoffBase with every attempt, up to Jobs.BackoffMax.
+	_, err := r.pg.Pool.Exec(ctx, `UPDATE embedding_outbox SET
+			error = $3,
+			locked_until = NULL,
+			run_at = now() + LEAST($4::bigint * power(2, attempts - 1), $5::bigint) * interval '1 millisecond'
+		WHERE movie_id = $1 AND token = $2`,
+		row.movieID, row.token, message, r.config.Jobs.BackoffBase.Milliseconds(), r.config.Jobs.BackoffMax.Milliseconds())
+	if err != nil {
+		r.logger.Error(fmt.Errorf("MovieRepo - failEmbedding - %w", err))
+	}
+}
+
+// releaseEmbedding hands a claimed movie back without counting the attempt,
+// when the worker stops while embedding it.
+func (r *MovieRepo) releaseEmbedding(row outboxRow) {
+	_, err := r.pg.Pool.Exec(tenant.WithAll(context.Background()), `UPDATE embedding_outbox SET
+			attempts = GREATEST(attempts - 1, 0),
+			locked_until = NULL
+		WHERE movie_id = $1 AND token = $2`, row.movieID, row.token)
+	if err != nil {
+		r.logger.Error(fmt.Errorf("MovieRepo - releaseEmbedding - %w", err))
+	}
+}
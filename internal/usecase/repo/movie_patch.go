@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/jsonpatch"
+)
+
+// Patch applies a JSON Merge Patch or JSON Patch to the JSON form of a movie
+// and stores the result. Fields the patch does not mention keep their
+// values, and the movie is only embedded again when its document changes.
+func (r *MovieRepo) Patch(ctx context.Context, req entity.MoviePatchRequest) (entity.Movie, error) {
+	current, err := r.GetSingle(ctx, entity.MovieSingleRequest{ID: req.ID})
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	if err = checkVersion(req.Version, current.Version); err != nil {
+		return entity.Movie{}, err
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return entity.Movie{}, err
+	}
+
+	var patched []byte
+	switch req.Format {
+	case entity.PatchJSON:
+		patched, err = jsonpatch.Apply(doc, req.Patch)
+	default:
+		patched, err = jsonpatch.MergePatch(doc, req.Patch)
+	}
+	if err != nil {
+		return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"%v", err)
+	}
+
+	var movie entity.Movie
+	if err = json.Unmarshal(patched, &movie); err != nil {
+		return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"patched movie is invalid: %v", err)
+	}
+
+	readOnly := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"id", current.ID, movie.ID},
+		{"created_at", current.CreatedAt, movie.CreatedAt},
+		{"updated_at", current.UpdatedAt, movie.UpdatedAt},
+		{"deleted_at", current.DeletedAt, movie.DeletedAt},
+		{"version", current.Version, movie.Version},
+		{"external_source", current.ExternalSource, movie.ExternalSource},
+		{"external_id", current.ExternalID, movie.ExternalID},
+		{"embedding_status", current.EmbeddingStatus, movie.EmbeddingStatus},
+		{"embedding_error", current.EmbeddingError, movie.EmbeddingError},
+	}
+	for _, field := range readOnly {
+		if field.before != field.after {
+			return entity.Movie{}, fmt.Errorf(config.ErrorBadRequest+"%s is read-only", field.name)
+		}
+	}
+
+	// The write fails with PRECONDITION_FAILED if the movie changed since it
+	// was read above.
+	return r.update(ctx, movie, revisionUpdate, &current)
+}
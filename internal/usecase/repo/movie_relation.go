@@ -0,0 +1,349 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/jackc/pgx/v4"
+)
+
+// movieCreditRoles lists the roles a person can have in a movie.
+var movieCreditRoles = map[string]struct{}{
+	"actor":    {},
+	"director": {},
+	"writer":   {},
+	"producer": {},
+	"composer": {},
+}
+
+// resolveRelations replaces the genre and person references of movie, given
+// by id or genre slug, with the stored records so the embedding template can
+// use their names. Unknown references are reported as bad requests.
+func (r *MovieRepo) resolveRelations(ctx context.Context, movie *entity.Movie) error {
+	genres := make([]entity.Genre, 0, len(movie.Genres))
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(movie.Genres) > 0 {
+		keys := make([]string, 0, len(movie.Genres))
+		for _, genre := range movie.Genres {
+			switch {
+			case genre.ID != "":
+				keys = append(keys, genre.ID)
+			case genre.Slug != "":
+				keys = append(keys, genre.Slug)
+			default:
+				return fmt.Errorf(config.ErrorBadRequest + "genre requires id or slug")
+			}
+		}
+
+		qeury, args, err := r.pg.Builder.
+			Select(`id, slug, name_uz, name_en, name_ru`).
+			From("genres").
+			Where("(id::text = ANY(?) OR slug = ANY(?))", keys, keys).
+			Where(scope).
+			OrderBy("slug").ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		found := map[string]struct{}{}
+		for rows.Next() {
+			var genre entity.Genre
+			if err = rows.Scan(&genre.ID, &genre.Slug, &genre.NameUz, &genre.NameEn, &genre.NameRu); err != nil {
+				return err
+			}
+
+			found[genre.ID] = struct{}{}
+			found[genre.Slug] = struct{}{}
+			genres = append(genres, genre)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if _, ok := found[key]; !ok {
+				return fmt.Errorf(config.ErrorBadRequest+"unknown genre %q", key)
+			}
+		}
+	}
+
+	movie.Genres = genres
+
+	if len(movie.Cast) == 0 {
+		movie.Cast = []entity.MovieCredit{}
+		return nil
+	}
+
+	ids := make([]string, 0, len(movie.Cast))
+	for i, credit := range movie.Cast {
+		if credit.PersonID == "" {
+			return fmt.Errorf(config.ErrorBadRequest + "cast entry requires person_id")
+		}
+		if _, ok := movieCreditRoles[credit.Role]; !ok {
+			return fmt.Errorf(config.ErrorBadRequest+"unknown cast role %q", credit.Role)
+		}
+		if credit.Position == 0 {
+			movie.Cast[i].Position = i + 1
+		}
+
+		ids = append(ids, credit.PersonID)
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, full_name`).
+		From("people").
+		Where("id::text = ANY(?)", ids).
+		Where(scope).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err = rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		names[id] = name
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i, credit := range movie.Cast {
+		name, ok := names[credit.PersonID]
+		if !ok {
+			return fmt.Errorf(config.ErrorBadRequest+"unknown person %q", credit.PersonID)
+		}
+		movie.Cast[i].FullName = name
+	}
+
+	sort.SliceStable(movie.Cast, func(i, j int) bool {
+		return movie.Cast[i].Position < movie.Cast[j].Position
+	})
+
+	return nil
+}
+
+// saveRelations replaces the genre and cast links of movie inside tx.
+func (r *MovieRepo) saveRelations(ctx context.Context, tx pgx.Tx, movie entity.Movie) error {
+	for _, table := range []string{"movie_genres", "movie_people"} {
+		qeury, args, err := r.pg.Builder.Delete(table).Where("movie_id = ?", movie.ID).ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, qeury, args...); err != nil {
+			return err
+		}
+	}
+
+	if len(movie.Genres) > 0 {
+		insert := r.pg.Builder.Insert("movie_genres").Columns(`movie_id, genre_id`)
+		for _, genre := range movie.Genres {
+			insert = insert.Values(movie.ID, genre.ID)
+		}
+
+		qeury, args, err := insert.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, qeury, args...); err != nil {
+			return err
+		}
+	}
+
+	if len(movie.Cast) > 0 {
+		insert := r.pg.Builder.Insert("movie_people").Columns(`movie_id, person_id, role, character_name, position`)
+		for _, credit := range movie.Cast {
+			insert = insert.Values(movie.ID, credit.PersonID, credit.Role, credit.Character, credit.Position)
+		}
+
+		qeury, args, err := insert.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, qeury, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRelations fills the genres and cast of movies with two queries.
+func (r *MovieRepo) loadRelations(ctx context.Context, movies []entity.Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(movies))
+	index := make(map[string]int, len(movies))
+	for i := range movies {
+		ids[i] = movies[i].ID
+		index[movies[i].ID] = i
+		movies[i].Genres = []entity.Genre{}
+		movies[i].Cast = []entity.MovieCredit{}
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`mg.movie_id, g.id, g.slug, g.name_uz, g.name_en, g.name_ru`).
+		From("movie_genres mg").
+		Join("genres g ON g.id = mg.genre_id").
+		Where("mg.movie_id::text = ANY(?)", ids).
+		OrderBy("g.slug").ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var (
+			movieID string
+			genre   entity.Genre
+		)
+		if err = rows.Scan(&movieID, &genre.ID, &genre.Slug, &genre.NameUz, &genre.NameEn, &genre.NameRu); err != nil {
+			rows.Close()
+			return err
+		}
+
+		i := index[movieID]
+		movies[i].Genres = append(movies[i].Genres, genre)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	qeury, args, err = r.pg.Builder.
+		Select(`mp.movie_id, p.id, p.full_name, mp.role, mp.character_name, mp.position`).
+		From("movie_people mp").
+		Join("people p ON p.id = mp.person_id").
+		Where("mp.movie_id::text = ANY(?)", ids).
+		OrderBy("mp.position").ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err = r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			movieID string
+			credit  entity.MovieCredit
+		)
+		if err = rows.Scan(&movieID, &credit.PersonID, &credit.FullName, &credit.Role, &credit.Character, &credit.Position); err != nil {
+			return err
+		}
+
+		i := index[movieID]
+		movies[i].Cast = append(movies[i].Cast, credit)
+	}
+
+	return rows.Err()
+}
+
+// bumpLinkedMovies raises the version of the movies linked to a genre or
+// person through linkTable in tx, since their responses carry its names and
+// would otherwise keep their ETags. It returns the ids of the movies.
+func bumpLinkedMovies(ctx context.Context, tx pgx.Tx, linkTable, linkColumn, id string) ([]string, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(`UPDATE movies SET version = version + 1
+		WHERE id IN (SELECT movie_id FROM %s WHERE %s = $1)
+		RETURNING id::text`, linkTable, linkColumn), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var movieID string
+		if err = rows.Scan(&movieID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, movieID)
+	}
+
+	return ids, rows.Err()
+}
+
+// relationFilters turns the "genre" and "person" pseudo-columns of a movie
+// list request into EXISTS conditions over the link tables and returns the
+// remaining filters untouched. Genres match by id or slug. Only eq, in and
+// not_in are supported on them.
+func relationFilters(filters []entity.Filter) ([]entity.Filter, squirrel.And, error) {
+	var (
+		rest  = make([]entity.Filter, 0, len(filters))
+		where = squirrel.And{}
+	)
+
+	for _, filter := range filters {
+		var exists string
+		switch filter.Column {
+		case "genre":
+			exists = `EXISTS (SELECT 1 FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id
+				WHERE mg.movie_id = movies.id AND (g.id::text = ANY(?) OR g.slug = ANY(?)))`
+		case "person":
+			exists = `EXISTS (SELECT 1 FROM movie_people mp
+				WHERE mp.movie_id = movies.id AND mp.person_id::text = ANY(?))`
+		default:
+			rest = append(rest, filter)
+			continue
+		}
+
+		values := []string{filter.Value}
+		switch filter.Type {
+		case "eq":
+		case "in", "not_in":
+			values = values[:0]
+			for _, value := range strings.Split(filter.Value, ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+			if filter.Type == "not_in" {
+				exists = "NOT " + exists
+			}
+		default:
+			return nil, nil, fmt.Errorf(config.ErrorBadRequest+"cannot filter by %s with %q, only eq, in and not_in", filter.Column, filter.Type)
+		}
+
+		args := []interface{}{values}
+		if filter.Column == "genre" {
+			args = append(args, values)
+		}
+		where = append(where, squirrel.Expr(exists, args...))
+	}
+
+	return rest, where, nil
+}
@@ -0,0 +1,253 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/audit"
+	"github.com/jackc/pgx/v4"
+)
+
+// Actions recorded in movie_revisions.
+const (
+	revisionCreate      = "create"
+	revisionUpdate      = "update"
+	revisionUpdateField = "update_field"
+	revisionDelete      = "delete"
+	revisionRestore     = "restore"
+	revisionRevert      = "revert"
+	revisionMerge       = "merge"
+)
+
+// unauditedFields are the JSON fields of entity.Movie that are not part of
+// its content and therefore never show up in a diff.
+var unauditedFields = map[string]struct{}{
+	"created_at":       {},
+	"updated_at":       {},
+	"distance":         {},
+	"explain":          {},
+	"version":          {},
+	"embedding_status": {},
+	"embedding_error":  {},
+	"duplicates":       {},
+}
+
+// lockMovie loads the movie with the given id inside tx and locks its row
+// until the transaction ends. Genres and cast are read outside of tx.
+func (r *MovieRepo) lockMovie(ctx context.Context, tx pgx.Tx, id string, scope, state squirrel.Sqlizer) (entity.Movie, error) {
+	var movie entity.Movie
+
+	qeury, args, err := r.pg.Builder.Select(movieColumns).From("movies").
+		Where("id = ?", id).Where(scope).Where(state).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return movie, err
+	}
+
+	if err = scanMovie(tx.QueryRow(ctx, qeury, args...), &movie); err != nil {
+		return movie, err
+	}
+
+	movies := []entity.Movie{movie}
+	if err = r.loadRelations(ctx, movies); err != nil {
+		return movie, err
+	}
+
+	return movies[0], nil
+}
+
+// recordRevision stores the change from before (nil for a new movie) to
+// after as the next revision of the movie. Edits that change nothing are
+// not recorded.
+func (r *MovieRepo) recordRevision(ctx context.Context, tx pgx.Tx, action string, before *entity.Movie, after entity.Movie) error {
+	diff, err := diffMovies(before, after)
+	if err != nil {
+		return err
+	}
+
+	if len(diff) == 0 && (action == revisionUpdate || action == revisionUpdateField) {
+		return nil
+	}
+
+	after.Distance = 0
+	after.Explain = nil
+	after.Duplicates = nil
+
+	snapshot, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO movie_revisions (tenant_id, movie_id, revision, action, actor, diff, snapshot)
+		SELECT (SELECT tenant_id FROM movies WHERE id = $1), $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5
+		FROM movie_revisions WHERE movie_id = $1`,
+		after.ID, action, audit.Actor(ctx), diffJSON, snapshot)
+
+	return err
+}
+
+// diffMovies compares the JSON representation of two movies field by field.
+func diffMovies(before *entity.Movie, after entity.Movie) (map[string]entity.FieldChange, error) {
+	oldFields := map[string]json.RawMessage{}
+	if before != nil {
+		if err := movieJSONFields(*before, oldFields); err != nil {
+			return nil, err
+		}
+	}
+
+	newFields := map[string]json.RawMessage{}
+	if err := movieJSONFields(after, newFields); err != nil {
+		return nil, err
+	}
+
+	diff := map[string]entity.FieldChange{}
+	for name, value := range newFields {
+		old, ok := oldFields[name]
+		if ok && bytes.Equal(old, value) {
+			continue
+		}
+
+		change := entity.FieldChange{New: value}
+		if ok {
+			change.Old = old
+		}
+		diff[name] = change
+	}
+
+	for name, old := range oldFields {
+		if _, ok := newFields[name]; !ok {
+			diff[name] = entity.FieldChange{Old: old}
+		}
+	}
+
+	return diff, nil
+}
+
+func movieJSONFields(movie entity.Movie, fields map[string]json.RawMessage) error {
+	data, err := json.Marshal(movie)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for name := range unauditedFields {
+		delete(fields, name)
+	}
+
+	return nil
+}
+
+// GetHistory lists the revisions of a movie, newest first.
+func (r *MovieRepo) GetHistory(ctx context.Context, req entity.MovieHistoryRequest) (entity.MovieRevisionList, error) {
+	response := entity.MovieRevisionList{Items: []entity.MovieRevision{}}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	where := squirrel.And{squirrel.Eq{"movie_id": req.MovieID}, scope}
+
+	qeury, args, err := r.pg.Builder.
+		Select("movie_id, revision, action, actor, diff, created_at").
+		From("movie_revisions").
+		Where(where).
+		OrderBy("revision DESC").
+		Limit(uint64(req.Limit)).Offset(uint64((req.Page - 1) * req.Limit)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item      entity.MovieRevision
+			diff      []byte
+			createdAt time.Time
+		)
+
+		err = rows.Scan(&item.MovieID, &item.Revision, &item.Action, &item.Actor, &diff, &createdAt)
+		if err != nil {
+			return response, err
+		}
+
+		if err = json.Unmarshal(diff, &item.Diff); err != nil {
+			return response, err
+		}
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("movie_revisions").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Revert restores the content a movie had after the given revision. The
+// revert is an update of its own: it re-embeds the movie and is recorded as
+// a new revision.
+func (r *MovieRepo) Revert(ctx context.Context, req entity.MovieRevertRequest) (entity.Movie, error) {
+	var (
+		movie    entity.Movie
+		snapshot []byte
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return movie, err
+	}
+
+	qeury, args, err := r.pg.Builder.Select("snapshot").From("movie_revisions").
+		Where(squirrel.Eq{"movie_id": req.MovieID, "revision": req.Revision}).Where(scope).ToSql()
+	if err != nil {
+		return movie, err
+	}
+
+	if err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&snapshot); err != nil {
+		return movie, err
+	}
+
+	if err = json.Unmarshal(snapshot, &movie); err != nil {
+		return movie, err
+	}
+	movie.ID = req.MovieID
+	movie.Version = 0
+
+	return r.update(ctx, movie, revisionRevert, nil)
+}
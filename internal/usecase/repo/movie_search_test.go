@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/rerank"
+)
+
+// searchHits are the hits of a search in fusion order; the local reranker
+// moves the ones naming both query words to the front of their window.
+func searchHits(n int) []entity.Movie {
+	names := []string{"Star Wars", "Star Trek", "War Games", "Alien", "Wars of the Star", "Heat"}
+
+	hits := make([]entity.Movie, n)
+	for i := range hits {
+		hits[i] = entity.Movie{
+			ID:     fmt.Sprintf("m%02d", i),
+			NameEn: names[i%len(names)],
+		}
+	}
+
+	return hits
+}
+
+// searchKey is the sort key Search selects for a hit: its score and id.
+func searchKey(i int, movie entity.Movie) []*string {
+	score := fmt.Sprintf("%.2f", 1-float64(i)/100)
+	id := movie.ID
+	return []*string{&score, &id}
+}
+
+// fetchWindow returns what the Search query returns for cur: up to window+1
+// hits after the cursor in fusion order, or before it in reverse order when
+// paging back.
+func fetchWindow(hits []entity.Movie, cur cursor, window int) ([]entity.Movie, [][]*string) {
+	position := -1
+	if len(cur.Values) > 0 {
+		for i := range hits {
+			if hits[i].ID == *cur.Values[1] {
+				position = i
+			}
+		}
+	}
+
+	var (
+		items []entity.Movie
+		keys  [][]*string
+	)
+	add := func(i int) {
+		items = append(items, hits[i])
+		keys = append(keys, searchKey(i, hits[i]))
+	}
+
+	if cur.Prev {
+		for i := position - 1; i >= 0 && len(items) <= window; i-- {
+			add(i)
+		}
+	} else {
+		for i := position + 1; i < len(hits) && len(items) <= window; i++ {
+			add(i)
+		}
+	}
+
+	return items, keys
+}
+
+func ids(movies []entity.Movie) []string {
+	list := make([]string, len(movies))
+	for i := range movies {
+		list[i] = movies[i].ID
+	}
+	return list
+}
+
+// TestSearchPagesThroughRerankedWindows pages through search hits reranked
+// by the local reranker in windows wider than a page.
+func TestSearchPagesThroughRerankedWindows(t *testing.T) {
+	const (
+		query  = "star wars"
+		window = 5
+		limit  = 2
+	)
+
+	cursors, err := newCursorCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Rerank.Timeout = time.Second
+
+	r := &MovieRepo{
+		reranker: rerank.NewLocal(),
+		config:   cfg,
+		logger:   logger.New("error"),
+		cursors:  cursors,
+	}
+
+	ctx := context.Background()
+	hits := searchHits(23)
+	scope := cursorScope("search", query, r.reranker.Name(), window)
+
+	page := func(token string) entity.MovieList {
+		t.Helper()
+
+		var cur cursor
+		if token != "" {
+			if cur, err = cursors.decode(token, scope, 2); err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+		}
+
+		var response entity.MovieList
+		var keys [][]*string
+		response.Items, keys = fetchWindow(hits, cur, window)
+
+		rerankErr, err := r.searchPage(ctx, &response, keys, cur, query, scope, window, limit)
+		if err != nil || rerankErr != nil {
+			t.Fatalf("searchPage() error = %v, rerank error = %v", err, rerankErr)
+		}
+		if len(response.Items) > limit {
+			t.Fatalf("page has %d hits, want at most %d", len(response.Items), limit)
+		}
+
+		return response
+	}
+
+	// Every window is expected in the order the local reranker gives it.
+	var want []string
+	for start := 0; start < len(hits); start += window {
+		end := min(start+window, len(hits))
+		windowHits := append([]entity.Movie{}, hits[start:end]...)
+
+		docs := make([]rerank.Document, len(windowHits))
+		for i, hit := range windowHits {
+			docs[i] = rerank.Document{ID: hit.ID, Text: hit.NameEn}
+		}
+		scores, err := rerank.NewLocal().Rerank(ctx, query, docs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		order := make([]int, len(windowHits))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+		for _, i := range order {
+			want = append(want, windowHits[i].ID)
+		}
+	}
+
+	var (
+		forward [][]string
+		got     []string
+		token   string
+	)
+	for {
+		response := page(token)
+		forward = append(forward, ids(response.Items))
+		got = append(got, ids(response.Items)...)
+
+		if len(forward) > len(hits) {
+			t.Fatal("paging forward does not end")
+		}
+		if response.NextCursor == "" {
+			token = response.PrevCursor
+			break
+		}
+		token = response.NextCursor
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("paging forward returned %v, want %v", got, want)
+	}
+	if reflect.DeepEqual(want, ids(hits)) {
+		t.Fatal("the reranker kept the fusion order, the test proves nothing")
+	}
+
+	// Paging back from the last page returns the same pages in reverse.
+	for i := len(forward) - 2; i >= 0; i-- {
+		if token == "" {
+			t.Fatalf("page %d has no previous cursor", i+1)
+		}
+
+		response := page(token)
+		if got := ids(response.Items); !reflect.DeepEqual(got, forward[i]) {
+			t.Fatalf("paging back to page %d returned %v, want %v", i, got, forward[i])
+		}
+		token = response.PrevCursor
+	}
+	if token != "" {
+		t.Error("the first page has a previous cursor")
+	}
+}
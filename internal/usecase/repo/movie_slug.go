@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/slug"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/jackc/pgx/v4"
+)
+
+// movieSlugBase derives the slug a movie would get from its names, preferring
+// the English one.
+func movieSlugBase(movie entity.Movie) string {
+	for _, name := range []string{movie.NameEn, movie.NameUz, movie.NameRu} {
+		if s := slug.Make(name); s != "" {
+			return s
+		}
+	}
+
+	return "movie"
+}
+
+// assignSlug sets movie.Slug inside tx. An explicit slug is normalized and
+// must be free; otherwise a unique slug is generated from the names. previous
+// is the stored state of the movie on update, nil on create: its slug is kept
+// unless the names changed, and a replaced slug is kept in the history so
+// requests for it can be redirected.
+func (r *MovieRepo) assignSlug(ctx context.Context, tx pgx.Tx, movie *entity.Movie, previous *entity.Movie) error {
+	tenantID := tenant.ID(ctx)
+	if tenantID == "" {
+		return fmt.Errorf("tenant is not resolved")
+	}
+
+	// Clients that send back the slug they read are not asking to pin it.
+	if previous != nil && movie.Slug == previous.Slug {
+		movie.Slug = ""
+	}
+
+	switch {
+	case movie.Slug != "":
+		movie.Slug = slug.Make(movie.Slug)
+		if movie.Slug == "" {
+			return fmt.Errorf(config.ErrorBadRequest + "slug must contain letters or digits")
+		}
+
+		taken, err := takenSlugs(ctx, tx, tenantID, movie.ID, movie.Slug)
+		if err != nil {
+			return err
+		}
+		if taken[movie.Slug] {
+			return fmt.Errorf(config.ErrorBadRequest+"slug %q is already taken", movie.Slug)
+		}
+	case previous != nil && movieSlugBase(*previous) == movieSlugBase(*movie):
+		movie.Slug = previous.Slug
+	default:
+		base := movieSlugBase(*movie)
+
+		taken, err := takenSlugs(ctx, tx, tenantID, movie.ID, base)
+		if err != nil {
+			return err
+		}
+
+		movie.Slug = base
+		for n := 2; taken[movie.Slug]; n++ {
+			movie.Slug = base + "-" + strconv.Itoa(n)
+		}
+	}
+
+	if previous == nil || previous.Slug == movie.Slug {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `INSERT INTO movie_slug_history (tenant_id, slug, movie_id) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, slug) DO UPDATE SET movie_id = EXCLUDED.movie_id, created_at = now()`,
+		tenantID, previous.Slug, movie.ID)
+	if err != nil {
+		return err
+	}
+
+	// A movie renamed back to one of its earlier slugs takes it out of the history.
+	_, err = tx.Exec(ctx, `DELETE FROM movie_slug_history WHERE tenant_id = $1 AND slug = $2 AND movie_id = $3`,
+		tenantID, movie.Slug, movie.ID)
+
+	return err
+}
+
+// takenSlugs returns the slugs equal to one of bases or of the form base-N
+// that are used, currently or in the history, by movies other than movieID.
+func takenSlugs(ctx context.Context, tx pgx.Tx, tenantID, movieID string, bases ...string) (map[string]bool, error) {
+	patterns := make([]string, len(bases))
+	for i, base := range bases {
+		patterns[i] = escapeLike(base) + "-%"
+	}
+
+	rows, err := tx.Query(ctx, `SELECT slug FROM movies
+		WHERE tenant_id = $1 AND id::text <> $2 AND (slug = ANY($3) OR slug LIKE ANY($4))
+		UNION
+		SELECT slug FROM movie_slug_history
+		WHERE tenant_id = $1 AND movie_id::text <> $2 AND (slug = ANY($3) OR slug LIKE ANY($4))`,
+		tenantID, movieID, bases, patterns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var s string
+		if err = rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		taken[s] = true
+	}
+
+	return taken, rows.Err()
+}
@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type PersonRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewPersonRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *PersonRepo {
+	return &PersonRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *PersonRepo) Create(ctx context.Context, req entity.Person) (entity.Person, error) {
+	req.ID = uuid.NewString()
+	req.FullName = strings.TrimSpace(req.FullName)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("people").
+		Columns(`id, tenant_id, full_name`).
+		Values(req.ID, scope["tenant_id"], req.FullName).
+		Suffix("RETURNING created_at, updated_at").ToSql()
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return req, nil
+}
+
+func (r *PersonRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Person, error) {
+	var (
+		response             = entity.Person{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, full_name, created_at, updated_at`).
+		From("people").
+		Where("id = ?", req.ID).
+		Where(scope).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.FullName, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *PersonRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.PersonList, error) {
+	var (
+		response             = entity.PersonList{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, full_name, created_at, updated_at`).
+		From("people").
+		Where(scope)
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, personFields, req)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, scope)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Person
+		err = rows.Scan(&item.ID, &item.FullName, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("people").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *PersonRepo) Update(ctx context.Context, req entity.Person) (entity.Person, error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	mp := map[string]interface{}{
+		"full_name":  strings.TrimSpace(req.FullName),
+		"updated_at": "now()",
+	}
+
+	qeury, args, err := r.pg.Builder.Update("people").SetMap(mp).Where("id = ?", req.ID).Where(scope).ToSql()
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	n, err := tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	if n.RowsAffected() == 0 {
+		return entity.Person{}, pgx.ErrNoRows
+	}
+
+	// The embedding documents of the linked movies show their name.
+	ids, err := bumpLinkedMovies(ctx, tx, "movie_people", "person_id", req.ID)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	if err = queueEmbeddings(ctx, tx, ids); err != nil {
+		return entity.Person{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return entity.Person{}, err
+	}
+
+	return r.GetSingle(ctx, entity.Id{ID: req.ID})
+}
+
+// Delete removes the person from the movies it is linked to and deletes it.
+// Those movies get a new version and are queued for the embedding workers,
+// since their documents change.
+func (r *PersonRepo) Delete(ctx context.Context, req entity.Id) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	qeury, args, err := r.pg.Builder.Select("id").From("people").Where("id = ?", req.ID).Where(scope).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ids, err := bumpLinkedMovies(ctx, tx, "movie_people", "person_id", id)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM movie_people WHERE person_id = $1", id); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM people WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	if err = queueEmbeddings(ctx, tx, ids); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,291 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type SynonymRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewSynonymRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *SynonymRepo {
+	return &SynonymRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *SynonymRepo) Create(ctx context.Context, req entity.Synonym) (entity.Synonym, error) {
+	req.ID = uuid.NewString()
+	req.Expansions = cleanPhrases(req.Expansions)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("synonyms").
+		Columns(`id, tenant_id, term, expansions`).
+		Values(req.ID, scope["tenant_id"], strings.TrimSpace(req.Term), req.Expansions).
+		Suffix("RETURNING term, created_at, updated_at").ToSql()
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&req.Term, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return req, nil
+}
+
+func (r *SynonymRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Synonym, error) {
+	var (
+		response             = entity.Synonym{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, term, expansions, created_at, updated_at`).
+		From("synonyms").
+		Where("id = ?", req.ID).
+		Where(scope).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.Term, &response.Expansions, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *SynonymRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.SynonymList, error) {
+	var (
+		response             = entity.SynonymList{}
+		createdAt, updatedAt time.Time
+	)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, term, expansions, created_at, updated_at`).
+		From("synonyms").
+		Where(scope)
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, synonymFields, req)
+	if err != nil {
+		return response, err
+	}
+	where = append(where, scope)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Synonym
+		err = rows.Scan(&item.ID, &item.Term, &item.Expansions, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("synonyms").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *SynonymRepo) Update(ctx context.Context, req entity.Synonym) (entity.Synonym, error) {
+	req.Expansions = cleanPhrases(req.Expansions)
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	mp := map[string]interface{}{
+		"term":       strings.TrimSpace(req.Term),
+		"expansions": req.Expansions,
+		"updated_at": "now()",
+	}
+
+	qeury, args, err := r.pg.Builder.Update("synonyms").SetMap(mp).Where("id = ?", req.ID).Where(scope).ToSql()
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Synonym{}, err
+	}
+
+	if n.RowsAffected() == 0 {
+		return entity.Synonym{}, pgx.ErrNoRows
+	}
+
+	return r.GetSingle(ctx, entity.Id{ID: req.ID})
+}
+
+func (r *SynonymRepo) Delete(ctx context.Context, req entity.Id) error {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	qeury, args, err := r.pg.Builder.Delete("synonyms").Where("id = ?", req.ID).Where(scope).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Expand returns the phrases that synonyms of terms found in query stand for.
+func (r *SynonymRepo) Expand(ctx context.Context, query string) ([]string, error) {
+	return expandQuery(ctx, r.pg, query)
+}
+
+// expandQuery looks up every synonym whose term occurs in query as a whole
+// word sequence and returns their expansions, without duplicates.
+func expandQuery(ctx context.Context, pg *postgres.Postgres, query string) ([]string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query and terms are compared as lowercase words separated by single
+	// spaces, so "harry-potter" finds the term "harry potter".
+	words := " " + strings.Join(splitWords(query), " ") + " "
+
+	qeury, args, err := pg.Builder.
+		Select(`term, expansions`).
+		From("synonyms").
+		Where(`position(' ' || trim(regexp_replace(lower(term), '[^[:alnum:]]+', ' ', 'g')) || ' ' in ?) > 0`, words).
+		Where(scope).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		expansions []string
+		seen       = map[string]struct{}{}
+	)
+
+	for rows.Next() {
+		var (
+			term    string
+			phrases []string
+		)
+		if err = rows.Scan(&term, &phrases); err != nil {
+			return nil, err
+		}
+
+		// The database may split words differently than splitWords, so
+		// only keep terms that line up with its word boundaries.
+		if !strings.Contains(words, " "+strings.Join(splitWords(term), " ")+" ") {
+			continue
+		}
+
+		for _, phrase := range phrases {
+			key := strings.ToLower(phrase)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			expansions = append(expansions, phrase)
+		}
+	}
+
+	return expansions, rows.Err()
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// cleanPhrases trims phrases and drops empty ones.
+func cleanPhrases(phrases []string) []string {
+	cleaned := make([]string, 0, len(phrases))
+
+	for _, phrase := range phrases {
+		phrase = strings.TrimSpace(phrase)
+		if phrase != "" {
+			cleaned = append(cleaned, phrase)
+		}
+	}
+
+	return cleaned
+}
@@ -0,0 +1,238 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/postgres"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type TenantRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewTenantRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *TenantRepo {
+	return &TenantRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Create stores a new tenant with a freshly generated API key and returns the key.
+func (r *TenantRepo) Create(ctx context.Context, req entity.Tenant) (entity.TenantWithKey, error) {
+	key := make([]byte, 24)
+	if _, err := rand.Read(key); err != nil {
+		return entity.TenantWithKey{}, err
+	}
+
+	response := entity.TenantWithKey{
+		Tenant: req,
+		ApiKey: hex.EncodeToString(key),
+	}
+	response.ID = uuid.NewString()
+	response.Name = strings.TrimSpace(req.Name)
+
+	qeury, args, err := r.pg.Builder.Insert("tenants").
+		Columns(`id, name, api_key_hash, max_movies, max_collections, requests_per_minute`).
+		Values(response.ID, response.Name, hashApiKey(response.ApiKey), req.MaxMovies, req.MaxCollections, req.RequestsPerMinute).
+		Suffix("RETURNING created_at, updated_at").ToSql()
+	if err != nil {
+		return entity.TenantWithKey{}, err
+	}
+
+	var createdAt, updatedAt time.Time
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		return entity.TenantWithKey{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *TenantRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Tenant, error) {
+	return r.get(ctx, squirrel.Eq{"id": req.ID})
+}
+
+// GetByApiKey resolves the tenant owning the given API key.
+func (r *TenantRepo) GetByApiKey(ctx context.Context, apiKey string) (entity.Tenant, error) {
+	return r.get(ctx, squirrel.Eq{"api_key_hash": hashApiKey(apiKey)})
+}
+
+func (r *TenantRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.TenantList, error) {
+	var (
+		response             = entity.TenantList{}
+		createdAt, updatedAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, name, max_movies, max_collections, requests_per_minute, created_at, updated_at`).
+		From("tenants")
+
+	qeuryBuilder, where, err := PrepareGetListQuery(qeuryBuilder, tenantFields, req)
+	if err != nil {
+		return response, err
+	}
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Tenant
+		err = rows.Scan(&item.ID, &item.Name, &item.MaxMovies, &item.MaxCollections, &item.RequestsPerMinute, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("tenants").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Update changes the name and quotas of a tenant; its API key is left as is.
+func (r *TenantRepo) Update(ctx context.Context, req entity.Tenant) (entity.Tenant, error) {
+	mp := map[string]interface{}{
+		"name":                strings.TrimSpace(req.Name),
+		"max_movies":          req.MaxMovies,
+		"max_collections":     req.MaxCollections,
+		"requests_per_minute": req.RequestsPerMinute,
+		"updated_at":          "now()",
+	}
+
+	qeury, args, err := r.pg.Builder.Update("tenants").SetMap(mp).Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return entity.Tenant{}, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Tenant{}, err
+	}
+
+	if n.RowsAffected() == 0 {
+		return entity.Tenant{}, pgx.ErrNoRows
+	}
+
+	return r.GetSingle(ctx, entity.Id{ID: req.ID})
+}
+
+func (r *TenantRepo) get(ctx context.Context, where squirrel.Sqlizer) (entity.Tenant, error) {
+	var (
+		response             = entity.Tenant{}
+		createdAt, updatedAt time.Time
+	)
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, name, max_movies, max_collections, requests_per_minute, created_at, updated_at`).
+		From("tenants").
+		Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.Name, &response.MaxMovies, &response.MaxCollections, &response.RequestsPerMinute, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Tenant{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func hashApiKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// tenantScope returns the tenant_id condition every tenant-owned query must
+// carry. It fails closed when the request has no resolved tenant.
+func tenantScope(ctx context.Context) (squirrel.Eq, error) {
+	id := tenant.ID(ctx)
+	if id == "" {
+		return nil, fmt.Errorf("tenant is not resolved")
+	}
+
+	return squirrel.Eq{"tenant_id": id}, nil
+}
+
+// checkQuota fails with QUOTA_EXCEEDED when the tenant already owns as many
+// rows of table as its quota in limitColumn allows; zero means unlimited.
+// It must run in the transaction that inserts the new row, see quotaUsage.
+func checkQuota(ctx context.Context, tx pgx.Tx, table, limitColumn string) error {
+	limit, count, err := quotaUsage(ctx, tx, table, limitColumn)
+	if err != nil {
+		return err
+	}
+
+	if limit > 0 && count >= limit {
+		return fmt.Errorf(config.ErrorQuotaExceeded+"the tenant may own at most %d %s", limit, table)
+	}
+
+	return nil
+}
+
+// quotaUsage returns the quota of the tenant in limitColumn and, when it is
+// limited, the number of rows of table the tenant owns. The tenant row stays
+// locked until tx ends, so concurrent inserts of the tenant count one after
+// another instead of all passing the same check.
+func quotaUsage(ctx context.Context, tx pgx.Tx, table, limitColumn string) (limit, count int, err error) {
+	id := tenant.ID(ctx)
+	if id == "" {
+		return 0, 0, fmt.Errorf("tenant is not resolved")
+	}
+
+	err = tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM tenants WHERE id = $1 FOR UPDATE`, limitColumn), id).Scan(&limit)
+	if err != nil || limit <= 0 {
+		return limit, 0, err
+	}
+
+	// Counted in a statement of its own, so the count sees the rows of the
+	// transactions the lock waited for.
+	err = tx.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(1) FROM %s WHERE tenant_id = $1`, table), id).Scan(&count)
+
+	return limit, count, err
+}
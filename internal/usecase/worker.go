@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abdulazizax/ai-embedding/config"
+	"github.com/abdulazizax/ai-embedding/internal/entity"
+	"github.com/abdulazizax/ai-embedding/pkg/audit"
+	"github.com/abdulazizax/ai-embedding/pkg/logger"
+	"github.com/abdulazizax/ai-embedding/pkg/progress"
+	"github.com/abdulazizax/ai-embedding/pkg/tenant"
+	"github.com/jackc/pgx/v4"
+)
+
+// maxHeartbeat is the longest time between heartbeats. Heartbeats also store
+// progress, so they are sent more often than the lease alone requires.
+const maxHeartbeat = 5 * time.Second
+
+// JobHandler runs one job. ctx carries the tenant and actor that queued the
+// job and reports progress.Report calls to the job; it is cancelled when the
+// job is cancelled or the worker stops. The returned result is stored as JSON,
+// also when the job fails or is cancelled.
+//
+// Errors are retried until the job runs out of attempts, except BAD_REQUEST
+// errors, which fail the job at once.
+type JobHandler func(ctx context.Context, job entity.Job) (interface{}, error)
+
+// Worker runs queued jobs of the kinds registered with it.
+type Worker struct {
+	repo     JobRepoI
+	config   *config.Config
+	logger   *logger.Logger
+	name     string
+	handlers map[string]JobHandler
+}
+
+// New -.
+func NewWorker(repo JobRepoI, config *config.Config, logger *logger.Logger) *Worker {
+	host, _ := os.Hostname()
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return &Worker{
+		repo:     repo,
+		config:   config,
+		logger:   logger,
+		name:     fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+		handlers: map[string]JobHandler{},
+	}
+}
+
+// Register sets the handler of a job kind. It must be called before Run.
+func (w *Worker) Register(kind string, handler JobHandler) {
+	w.handlers[kind] = handler
+}
+
+// Run polls for jobs with Jobs.Workers goroutines until ctx is cancelled.
+// Jobs still running then are handed back to the queue before Run returns.
+func (w *Worker) Run(ctx context.Context) {
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	workers := w.config.Jobs.Workers
+	if workers <= 0 || len(kinds) == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx, fmt.Sprintf("%s/%d", w.name, i), kinds)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) poll(ctx context.Context, name string, kinds []string) {
+	interval := w.config.Jobs.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for ctx.Err() == nil {
+		job, ok, err := w.repo.Claim(ctx, name, kinds, w.lease())
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error(err, "Worker - poll - Claim")
+		}
+
+		if ok {
+			w.run(ctx, name, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *Worker) lease() time.Duration {
+	if w.config.Jobs.Lease <= 0 {
+		return time.Minute
+	}
+
+	return w.config.Jobs.Lease
+}
+
+// run runs a claimed job and records its outcome. Bookkeeping uses a
+// context for all tenants, so it works for system jobs too and still
+// completes while the worker shuts down.
+func (w *Worker) run(ctx context.Context, name string, job entity.Job) {
+	background := tenant.WithAll(context.Background())
+
+	if job.CancelRequested {
+		w.finish(name, job, entity.JobCancelled, nil, "cancelled")
+		return
+	}
+
+	// The job was claimed again after its worker stopped sending heartbeats.
+	if job.Attempts > job.MaxAttempts {
+		w.finish(name, job, entity.JobFailed, nil, fmt.Sprintf("gave up after %d attempts: worker stopped responding", job.MaxAttempts))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobCtx = audit.WithActor(tenant.WithID(jobCtx, job.TenantID), job.Actor)
+
+	var done, total atomic.Int64
+	jobCtx = progress.WithReporter(jobCtx, func(d, t int) {
+		done.Store(int64(d))
+		total.Store(int64(t))
+	})
+
+	var (
+		cancelled, lost atomic.Bool
+		stop            = make(chan struct{})
+		stopped         = make(chan struct{})
+	)
+
+	go func() {
+		defer close(stopped)
+
+		interval := w.lease() / 3
+		if interval > maxHeartbeat {
+			interval = maxHeartbeat
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			current := entity.JobProgress{Done: int(done.Load()), Total: int(total.Load())}
+			cancelRequested, err := w.repo.Heartbeat(background, job.ID, name, current, w.lease())
+			if errors.Is(err, pgx.ErrNoRows) {
+				lost.Store(true)
+				cancel()
+				return
+			}
+			if err != nil {
+				w.logger.Error(err, "Worker - run - Heartbeat")
+				continue
+			}
+			if cancelRequested {
+				cancelled.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	result, err := w.call(jobCtx, job)
+	close(stop)
+	<-stopped
+
+	var resultJSON json.RawMessage
+	if result != nil {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr == nil {
+			resultJSON = data
+		} else if err == nil {
+			err = fmt.Errorf("encode result: %w", marshalErr)
+		}
+	}
+
+	switch {
+	case lost.Load():
+		w.logger.Warn("Worker - run - job %s was taken over by another worker", job.ID)
+	case cancelled.Load():
+		w.finish(name, job, entity.JobCancelled, resultJSON, "cancelled")
+	case err == nil:
+		w.finish(name, job, entity.JobSucceeded, resultJSON, "")
+	case ctx.Err() != nil:
+		if err := w.repo.Requeue(background, job.ID, name); err != nil {
+			w.logger.Error(err, "Worker - run - Requeue")
+		}
+	case strings.Contains(err.Error(), config.ErrorBadRequest) || job.Attempts >= job.MaxAttempts:
+		w.finish(name, job, entity.JobFailed, resultJSON, errorMessage(err))
+	default:
+		delay := w.backoff(job.Attempts)
+		w.logger.Warn("Worker - run - job %s failed, retrying in %s: %v", job.ID, delay, err)
+		if err := w.repo.Retry(background, job.ID, name, errorMessage(err), resultJSON, delay); err != nil {
+			w.logger.Error(err, "Worker - run - Retry")
+		}
+	}
+}
+
+// call runs the handler of the job, turning a panic into an error.
+func (w *Worker) call(ctx context.Context, job entity.Job) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	return w.handlers[job.Kind](ctx, job)
+}
+
+func (w *Worker) finish(name string, job entity.Job, state string, result json.RawMessage, message string) {
+	if err := w.repo.Finish(tenant.WithAll(context.Background()), job.ID, name, state, result, message); err != nil {
+		w.logger.Error(err, "Worker - finish - "+state)
+	}
+}
+
+// backoff is the delay before the next attempt after the given number of
+// attempts: Jobs.BackoffBase doubled for every attempt after the first, at
+// most Jobs.BackoffMax.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay, max := w.config.Jobs.BackoffBase, w.config.Jobs.BackoffMax
+
+	for i := 1; i < attempts && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+
+	return delay
+}
+
+// errorMessage drops the BAD_REQUEST code from an error for the job record.
+func errorMessage(err error) string {
+	msg := err.Error()
+	if i := strings.Index(msg, config.ErrorBadRequest); i >= 0 {
+		msg = msg[:i] + msg[i+len(config.ErrorBadRequest):]
+	}
+
+	return msg
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS movies_name_uz_trgm_idx;
+DROP INDEX IF EXISTS movies_name_en_trgm_idx;
+DROP INDEX IF EXISTS movies_name_ru_trgm_idx;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS movies_name_uz_trgm_idx ON movies USING GIN (name_uz gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movies_name_en_trgm_idx ON movies USING GIN (name_en gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movies_name_ru_trgm_idx ON movies USING GIN (name_ru gin_trgm_ops);
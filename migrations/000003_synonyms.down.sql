@@ -0,0 +1,3 @@
+ALTER TABLE movies DROP COLUMN IF EXISTS aliases;
+
+DROP TABLE IF EXISTS synonyms;
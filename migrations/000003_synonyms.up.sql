@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS synonyms (
+    id UUID PRIMARY KEY,
+    term VARCHAR(256) NOT NULL,
+    expansions TEXT[] NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    updated_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS synonyms_term_key ON synonyms (lower(term));
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS aliases TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_embedding_template_hash_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS embedding_template_hash;
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS movie_people;
+DROP TABLE IF EXISTS movie_genres;
+DROP TABLE IF EXISTS people;
+DROP TABLE IF EXISTS genres;
+
+DROP INDEX IF EXISTS movies_country_idx;
+DROP INDEX IF EXISTS movies_release_year_idx;
+
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS description_uz,
+    DROP COLUMN IF EXISTS description_en,
+    DROP COLUMN IF EXISTS description_ru,
+    DROP COLUMN IF EXISTS release_year,
+    DROP COLUMN IF EXISTS runtime_minutes,
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS poster_url;
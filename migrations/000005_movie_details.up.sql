@@ -0,0 +1,47 @@
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS description_uz TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description_en TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description_ru TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS release_year INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS runtime_minutes INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS country VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS poster_url TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS movies_release_year_idx ON movies (release_year);
+CREATE INDEX IF NOT EXISTS movies_country_idx ON movies (country);
+
+CREATE TABLE IF NOT EXISTS genres (
+    id UUID PRIMARY KEY,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name_uz VARCHAR(128) NOT NULL,
+    name_en VARCHAR(128) NOT NULL,
+    name_ru VARCHAR(128) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    updated_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+CREATE TABLE IF NOT EXISTS people (
+    id UUID PRIMARY KEY,
+    full_name VARCHAR(256) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    updated_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+CREATE TABLE IF NOT EXISTS movie_genres (
+    movie_id UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    genre_id UUID NOT NULL REFERENCES genres (id),
+    PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_genres_genre_id_idx ON movie_genres (genre_id);
+
+CREATE TABLE IF NOT EXISTS movie_people (
+    movie_id UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    person_id UUID NOT NULL REFERENCES people (id),
+    role VARCHAR(32) NOT NULL,
+    character_name VARCHAR(256) NOT NULL DEFAULT '',
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (movie_id, person_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS movie_people_person_id_idx ON movie_people (person_id);
@@ -0,0 +1,10 @@
+DO $$
+DECLARE
+    collection_name TEXT;
+BEGIN
+    FOR collection_name IN SELECT name FROM collections LOOP
+        EXECUTE format('DROP TABLE IF EXISTS %I', 'collection_' || collection_name);
+    END LOOP;
+END $$;
+
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS collections (
+    name VARCHAR(48) PRIMARY KEY,
+    fields JSONB NOT NULL,
+    dimension INT NOT NULL,
+    metric VARCHAR(16) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    updated_at timestamp NOT NULL DEFAULT 'now()'
+);
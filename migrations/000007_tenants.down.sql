@@ -0,0 +1,38 @@
+DROP POLICY IF EXISTS tenant_isolation ON collections;
+DROP POLICY IF EXISTS tenant_isolation ON people;
+DROP POLICY IF EXISTS tenant_isolation ON genres;
+DROP POLICY IF EXISTS tenant_isolation ON synonyms;
+DROP POLICY IF EXISTS tenant_isolation ON movies;
+
+ALTER TABLE collections NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE collections DISABLE ROW LEVEL SECURITY;
+ALTER TABLE people NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE people DISABLE ROW LEVEL SECURITY;
+ALTER TABLE genres NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE genres DISABLE ROW LEVEL SECURITY;
+ALTER TABLE synonyms NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE synonyms DISABLE ROW LEVEL SECURITY;
+ALTER TABLE movies NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE movies DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS collections_table_name_key;
+ALTER TABLE collections DROP CONSTRAINT IF EXISTS collections_pkey;
+ALTER TABLE collections ADD PRIMARY KEY (name);
+ALTER TABLE collections DROP COLUMN IF EXISTS table_name;
+ALTER TABLE collections DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS people_tenant_id_idx;
+ALTER TABLE people DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS genres_tenant_id_slug_key;
+ALTER TABLE genres DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE genres ADD CONSTRAINT genres_slug_key UNIQUE (slug);
+
+DROP INDEX IF EXISTS synonyms_tenant_id_term_key;
+ALTER TABLE synonyms DROP COLUMN IF EXISTS tenant_id;
+CREATE UNIQUE INDEX IF NOT EXISTS synonyms_term_key ON synonyms (lower(term));
+
+DROP INDEX IF EXISTS movies_tenant_id_created_at_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
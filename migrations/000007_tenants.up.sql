@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID PRIMARY KEY,
+    name VARCHAR(128) NOT NULL UNIQUE,
+    api_key_hash VARCHAR(64) UNIQUE,
+    max_movies INT NOT NULL DEFAULT 0,
+    max_collections INT NOT NULL DEFAULT 0,
+    requests_per_minute INT NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    updated_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+-- Rows created before tenants existed belong to the default tenant, which has no API key.
+INSERT INTO tenants (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants (id);
+ALTER TABLE movies ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS movies_tenant_id_created_at_idx ON movies (tenant_id, created_at);
+
+ALTER TABLE synonyms ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants (id);
+ALTER TABLE synonyms ALTER COLUMN tenant_id DROP DEFAULT;
+DROP INDEX IF EXISTS synonyms_term_key;
+CREATE UNIQUE INDEX IF NOT EXISTS synonyms_tenant_id_term_key ON synonyms (tenant_id, lower(term));
+
+ALTER TABLE genres ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants (id);
+ALTER TABLE genres ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_slug_key;
+CREATE UNIQUE INDEX IF NOT EXISTS genres_tenant_id_slug_key ON genres (tenant_id, slug);
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants (id);
+ALTER TABLE people ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS people_tenant_id_idx ON people (tenant_id);
+
+-- Collection tables are per tenant, so their names are stored instead of derived.
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants (id);
+ALTER TABLE collections ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS table_name VARCHAR(63);
+UPDATE collections SET table_name = 'collection_' || name WHERE table_name IS NULL;
+ALTER TABLE collections ALTER COLUMN table_name SET NOT NULL;
+ALTER TABLE collections DROP CONSTRAINT IF EXISTS collections_pkey;
+ALTER TABLE collections ADD PRIMARY KEY (tenant_id, name);
+CREATE UNIQUE INDEX IF NOT EXISTS collections_table_name_key ON collections (table_name);
+
+-- Row-level security is a second line of defence behind the tenant_id
+-- predicates in every query. The policies only restrict sessions that set
+-- app.tenant_id, which the application does when it runs with
+-- PG_ROW_LEVEL_SECURITY enabled; background workers leave it unset.
+ALTER TABLE movies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE movies FORCE ROW LEVEL SECURITY;
+ALTER TABLE synonyms ENABLE ROW LEVEL SECURITY;
+ALTER TABLE synonyms FORCE ROW LEVEL SECURITY;
+ALTER TABLE genres ENABLE ROW LEVEL SECURITY;
+ALTER TABLE genres FORCE ROW LEVEL SECURITY;
+ALTER TABLE people ENABLE ROW LEVEL SECURITY;
+ALTER TABLE people FORCE ROW LEVEL SECURITY;
+ALTER TABLE collections ENABLE ROW LEVEL SECURITY;
+ALTER TABLE collections FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON movies
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+CREATE POLICY tenant_isolation ON synonyms
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+CREATE POLICY tenant_isolation ON genres
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+CREATE POLICY tenant_isolation ON people
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+CREATE POLICY tenant_isolation ON collections
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS movie_slug_history;
+
+DROP INDEX IF EXISTS movies_tenant_slug_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS slug;
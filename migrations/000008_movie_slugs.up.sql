@@ -0,0 +1,29 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS slug VARCHAR(96);
+
+-- Existing movies get an ASCII slug with a short id suffix; it is unique
+-- without a lookup, and new slugs are transliterated by the application.
+UPDATE movies
+SET slug = COALESCE(NULLIF(trim(BOTH '-' FROM left(lower(regexp_replace(
+        CASE WHEN name_en <> '' THEN name_en ELSE name_uz END,
+        '[^a-zA-Z0-9]+', '-', 'g')), 80)), '') || '-', 'movie-') || left(id::text, 8)
+WHERE slug IS NULL;
+
+ALTER TABLE movies ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS movies_tenant_slug_idx ON movies (tenant_id, slug);
+
+-- Slugs a movie had before it was renamed; requests for them are redirected.
+CREATE TABLE IF NOT EXISTS movie_slug_history (
+    tenant_id UUID NOT NULL REFERENCES tenants (id),
+    slug VARCHAR(96) NOT NULL,
+    movie_id UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT 'now()',
+    PRIMARY KEY (tenant_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS movie_slug_history_movie_idx ON movie_slug_history (movie_id);
+
+ALTER TABLE movie_slug_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE movie_slug_history FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON movie_slug_history
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants (id),
+    movie_id UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(256) NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    -- The movie as it was after the change, genres and cast included.
+    snapshot JSONB NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    UNIQUE (movie_id, revision)
+);
+
+ALTER TABLE movie_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE movie_revisions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON movie_revisions
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
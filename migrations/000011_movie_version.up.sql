@@ -0,0 +1,2 @@
+-- Incremented on every change; exposed as the ETag of a movie.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS movies_tenant_id_external_key;
+
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS external_source,
+    DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,6 @@
+-- Identity of a movie in the catalog it was imported from; imports upsert by it.
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS external_source VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS external_id VARCHAR(128);
+
+CREATE UNIQUE INDEX IF NOT EXISTS movies_tenant_id_external_key ON movies (tenant_id, external_source, external_id);
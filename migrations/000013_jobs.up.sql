@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY,
+    -- NULL for system jobs, which no tenant can see.
+    tenant_id UUID REFERENCES tenants (id),
+    kind VARCHAR(64) NOT NULL,
+    -- queued, running, succeeded, failed or cancelled
+    state VARCHAR(16) NOT NULL DEFAULT 'queued',
+    payload JSONB NOT NULL DEFAULT '{}',
+    -- Uploaded data the job works on, e.g. the file of an import.
+    input BYTEA,
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    progress_done INT NOT NULL DEFAULT 0,
+    progress_total INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 3,
+    cancel_requested BOOLEAN NOT NULL DEFAULT false,
+    -- At most one queued or running job exists per unique key.
+    unique_key VARCHAR(128),
+    actor VARCHAR(256) NOT NULL,
+    run_at timestamp NOT NULL DEFAULT now(),
+    -- A running job whose lease expired is claimed again by another worker.
+    locked_by VARCHAR(128),
+    locked_until timestamp,
+    started_at timestamp,
+    finished_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS jobs_queued_run_at_idx ON jobs (run_at) WHERE state = 'queued';
+CREATE INDEX IF NOT EXISTS jobs_running_locked_until_idx ON jobs (locked_until) WHERE state = 'running';
+CREATE INDEX IF NOT EXISTS jobs_tenant_id_created_at_idx ON jobs (tenant_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_unique_key_active_key ON jobs (unique_key) WHERE state IN ('queued', 'running');
+
+ALTER TABLE jobs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE jobs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON jobs
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
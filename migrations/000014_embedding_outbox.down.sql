@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS embedding_outbox;
+
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS embedding_error,
+    DROP COLUMN IF EXISTS embedding_status;
@@ -0,0 +1,27 @@
+-- pending while the embedding of the current content is being generated,
+-- failed when generating it gave up. Search only compares ready vectors.
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS embedding_status VARCHAR(16) NOT NULL DEFAULT 'ready',
+    ADD COLUMN IF NOT EXISTS embedding_error TEXT NOT NULL DEFAULT '';
+
+-- Movies whose embedding has to be generated. Rows are written in the same
+-- transaction as the movie and removed once the vector is stored.
+CREATE TABLE IF NOT EXISTS embedding_outbox (
+    movie_id UUID PRIMARY KEY REFERENCES movies (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants (id),
+    -- Renewed whenever the movie is queued again, so a worker embedding an
+    -- older version does not remove the row.
+    token BIGSERIAL NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    run_at timestamp NOT NULL DEFAULT now(),
+    locked_until timestamp,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS embedding_outbox_run_at_idx ON embedding_outbox (run_at);
+
+ALTER TABLE embedding_outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE embedding_outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON embedding_outbox
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
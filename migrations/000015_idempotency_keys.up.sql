@@ -0,0 +1,24 @@
+-- Responses to write requests sent with an Idempotency-Key header, replayed
+-- when a client retries with the same key.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id UUID NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    -- Hash of the method, URL and body; a retry has to send the same request.
+    request_hash VARCHAR(64) NOT NULL,
+    -- NULL while the first request is still being processed.
+    status INT,
+    header JSONB,
+    body BYTEA,
+    created_at timestamp NOT NULL DEFAULT now(),
+    -- Until when the key is claimed by the running request, or its response
+    -- is replayed. Expired keys can be used again.
+    expires_at timestamp NOT NULL,
+    PRIMARY KEY (tenant_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
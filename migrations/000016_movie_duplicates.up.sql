@@ -0,0 +1,19 @@
+-- Movies whose embeddings are near duplicates, flagged by the embedding
+-- workers when a vector is stored. movie_a is the smaller id of the pair.
+CREATE TABLE IF NOT EXISTS movie_duplicates (
+    tenant_id UUID NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    movie_a UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    movie_b UUID NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    -- Cosine similarity of both embeddings.
+    similarity DOUBLE PRECISION NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (movie_a, movie_b)
+);
+
+CREATE INDEX IF NOT EXISTS movie_duplicates_movie_b_idx ON movie_duplicates (movie_b);
+CREATE INDEX IF NOT EXISTS movie_duplicates_tenant_id_idx ON movie_duplicates (tenant_id, similarity DESC);
+
+ALTER TABLE movie_duplicates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE movie_duplicates FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON movie_duplicates
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS movies_embedding_cosine_idx;
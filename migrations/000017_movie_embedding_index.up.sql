@@ -0,0 +1,2 @@
+-- Nearest neighbours by cosine distance, as the duplicate check looks them up.
+CREATE INDEX IF NOT EXISTS movies_embedding_cosine_idx ON movies USING hnsw (embedding vector_cosine_ops);
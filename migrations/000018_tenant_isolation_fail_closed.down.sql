@@ -0,0 +1,33 @@
+DROP POLICY IF EXISTS tenant_isolation ON movie_duplicates;
+CREATE POLICY tenant_isolation ON movie_duplicates
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON embedding_outbox;
+CREATE POLICY tenant_isolation ON embedding_outbox
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON jobs;
+CREATE POLICY tenant_isolation ON jobs
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movie_revisions;
+CREATE POLICY tenant_isolation ON movie_revisions
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movie_slug_history;
+CREATE POLICY tenant_isolation ON movie_slug_history
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON collections;
+CREATE POLICY tenant_isolation ON collections
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON people;
+CREATE POLICY tenant_isolation ON people
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON genres;
+CREATE POLICY tenant_isolation ON genres
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON synonyms;
+CREATE POLICY tenant_isolation ON synonyms
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movies;
+CREATE POLICY tenant_isolation ON movies
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id::text = current_setting('app.tenant_id', true));
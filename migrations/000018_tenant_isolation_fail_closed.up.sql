@@ -0,0 +1,37 @@
+-- Sessions that set neither app.tenant_id nor app.bypass_rls see no rows, so
+-- a query that lost its tenant fails closed. Background workers that act for
+-- all tenants set app.bypass_rls instead, as does the application when it
+-- runs with PG_ROW_LEVEL_SECURITY disabled.
+DROP POLICY IF EXISTS tenant_isolation ON movies;
+CREATE POLICY tenant_isolation ON movies
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON synonyms;
+CREATE POLICY tenant_isolation ON synonyms
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON genres;
+CREATE POLICY tenant_isolation ON genres
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON people;
+CREATE POLICY tenant_isolation ON people
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON collections;
+CREATE POLICY tenant_isolation ON collections
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movie_slug_history;
+CREATE POLICY tenant_isolation ON movie_slug_history
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movie_revisions;
+CREATE POLICY tenant_isolation ON movie_revisions
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON jobs;
+CREATE POLICY tenant_isolation ON jobs
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON embedding_outbox;
+CREATE POLICY tenant_isolation ON embedding_outbox
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
+DROP POLICY IF EXISTS tenant_isolation ON movie_duplicates;
+CREATE POLICY tenant_isolation ON movie_duplicates
+    USING (current_setting('app.bypass_rls', true) = 'on' OR tenant_id::text = current_setting('app.tenant_id', true));
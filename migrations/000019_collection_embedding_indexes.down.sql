@@ -0,0 +1,2 @@
+-- The indexes are dropped with their collection tables.
+SELECT 1;
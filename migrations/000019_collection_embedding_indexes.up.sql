@@ -0,0 +1,19 @@
+-- Collections created before their tables were indexed get an index for the
+-- metric they are searched with.
+SELECT set_config('app.bypass_rls', 'on', false);
+
+DO $$
+DECLARE
+    coll RECORD;
+BEGIN
+    FOR coll IN SELECT table_name, metric FROM collections LOOP
+        IF NOT EXISTS (
+            SELECT 1 FROM pg_indexes
+            WHERE schemaname = current_schema() AND tablename = coll.table_name AND indexdef LIKE '%USING hnsw%'
+        ) THEN
+            EXECUTE format('CREATE INDEX ON %I USING hnsw (embedding %s)', coll.table_name,
+                CASE coll.metric WHEN 'l2' THEN 'vector_l2_ops' WHEN 'inner_product' THEN 'vector_ip_ops' ELSE 'vector_cosine_ops' END);
+        END IF;
+    END LOOP;
+END
+$$;
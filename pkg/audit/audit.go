@@ -0,0 +1,23 @@
+// Package audit carries the actor responsible for a change through context.
+package audit
+
+import "context"
+
+// System is the actor of changes made without a request, e.g. by background jobs.
+const System = "system"
+
+type ctxKey struct{}
+
+// WithActor returns a copy of ctx that carries the actor name.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, or System.
+func Actor(ctx context.Context) string {
+	if actor, _ := ctx.Value(ctxKey{}).(string); actor != "" {
+		return actor
+	}
+
+	return System
+}
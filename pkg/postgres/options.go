@@ -1,6 +1,11 @@
 package postgres
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
 
 // Option -.
 type Option func(*Postgres)
@@ -25,3 +30,17 @@ func ConnTimeout(timeout time.Duration) Option {
 		c.connTimeout = timeout
 	}
 }
+
+// BeforeAcquire -.
+func BeforeAcquire(fn func(ctx context.Context, conn *pgx.Conn) bool) Option {
+	return func(c *Postgres) {
+		c.beforeAcquire = fn
+	}
+}
+
+// AfterConnect -.
+func AfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) Option {
+	return func(c *Postgres) {
+		c.afterConnect = fn
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -23,6 +24,9 @@ type Postgres struct {
 	connAttempts int
 	connTimeout  time.Duration
 
+	beforeAcquire func(ctx context.Context, conn *pgx.Conn) bool
+	afterConnect  func(ctx context.Context, conn *pgx.Conn) error
+
 	Builder squirrel.StatementBuilderType
 	Pool    *pgxpool.Pool
 }
@@ -48,6 +52,8 @@ func New(url string, opts ...Option) (*Postgres, error) {
 	}
 
 	poolConfig.MaxConns = int32(pg.maxPoolSize)
+	poolConfig.BeforeAcquire = pg.beforeAcquire
+	poolConfig.AfterConnect = pg.afterConnect
 
 	for pg.connAttempts > 0 {
 		pg.Pool, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
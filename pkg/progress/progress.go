@@ -0,0 +1,23 @@
+// Package progress lets long-running operations report how far they got
+// through context, without knowing who is listening.
+package progress
+
+import "context"
+
+// Reporter receives the number of items done and the total, zero when the
+// total is not known.
+type Reporter func(done, total int)
+
+type ctxKey struct{}
+
+// WithReporter returns a copy of ctx that carries reporter.
+func WithReporter(ctx context.Context, reporter Reporter) context.Context {
+	return context.WithValue(ctx, ctxKey{}, reporter)
+}
+
+// Report passes progress to the reporter carried by ctx, if any.
+func Report(ctx context.Context, done, total int) {
+	if reporter, _ := ctx.Value(ctxKey{}).(Reporter); reporter != nil {
+		reporter(done, total)
+	}
+}
@@ -0,0 +1,724 @@
+package records
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Arrow IPC streams are written and read without compression or dictionary
+// encoding, which is what Arrow libraries produce by default. Writers use
+// Utf8 for strings, Int64 for ints, and List of Utf8 or Float32 for lists;
+// readers also take the other integer and float widths, Bool, LargeUtf8 and
+// FixedSizeList.
+
+const (
+	arrowContinuation = 0xFFFFFFFF
+	arrowVersion      = 4 // MetadataVersion V5
+	arrowBatchRows    = 1024
+	arrowMaxBody      = 256 << 20
+)
+
+// MessageHeader members.
+const (
+	arrowSchema          = 1
+	arrowDictionaryBatch = 2
+	arrowRecordBatch     = 3
+)
+
+// Type members.
+const (
+	arrowInt           = 2
+	arrowFloatingPoint = 3
+	arrowUtf8          = 5
+	arrowBool          = 6
+	arrowList          = 12
+	arrowFixedSizeList = 16
+	arrowLargeUtf8     = 20
+)
+
+// Precision members.
+const (
+	arrowSingle = 1
+	arrowDouble = 2
+)
+
+type arrowWriter struct {
+	writer  io.Writer
+	columns []Column
+	rows    [][]interface{}
+	started bool
+}
+
+func newArrowWriter(w io.Writer, columns []Column) *arrowWriter {
+	return &arrowWriter{writer: w, columns: columns}
+}
+
+// Write buffers the record and writes a record batch every arrowBatchRows
+// records.
+func (a *arrowWriter) Write(values []interface{}) error {
+	for i, column := range a.columns {
+		var ok bool
+		switch column.Type {
+		case TypeString:
+			_, ok = values[i].(string)
+		case TypeInt:
+			_, ok = values[i].(int)
+		case TypeStrings:
+			_, ok = values[i].([]string)
+		case TypeFloats:
+			_, ok = values[i].([]float32)
+		}
+		if !ok {
+			return fmt.Errorf("column %s: unexpected %T", column.Name, values[i])
+		}
+	}
+
+	a.rows = append(a.rows, append([]interface{}(nil), values...))
+	if len(a.rows) >= arrowBatchRows {
+		return a.flush()
+	}
+
+	return nil
+}
+
+// Close writes the buffered records and the end-of-stream marker.
+func (a *arrowWriter) Close() error {
+	if err := a.flush(); err != nil {
+		return err
+	}
+
+	_, err := a.writer.Write(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, arrowContinuation), 0))
+	return err
+}
+
+func (a *arrowWriter) flush() error {
+	if !a.started {
+		a.started = true
+
+		fields := make(fbVector, len(a.columns))
+		for i, column := range a.columns {
+			fields[i] = arrowField(column)
+		}
+
+		// Endianness Little, fields.
+		if err := a.message(arrowSchema, fbTable{fbI16(0), fbRef(fields)}, nil); err != nil {
+			return err
+		}
+	}
+
+	if len(a.rows) == 0 {
+		return nil
+	}
+
+	var batch arrowBatchWriter
+	for i, column := range a.columns {
+		batch.column(column.Type, a.rows, i)
+	}
+
+	// Length, nodes, buffers.
+	header := fbTable{
+		fbI64(int64(len(a.rows))),
+		fbRef(fbStructs{count: len(batch.nodes) / 16, data: batch.nodes}),
+		fbRef(fbStructs{count: len(batch.buffers) / 16, data: batch.buffers}),
+	}
+	a.rows = a.rows[:0]
+
+	return a.message(arrowRecordBatch, header, batch.body)
+}
+
+// message writes an encapsulated message: the continuation marker, the
+// length of the metadata, the Message flatbuffer padded to 8 bytes, and
+// the body.
+func (a *arrowWriter) message(headerType uint8, header fbTable, body []byte) error {
+	// Version, header type, header, body length.
+	metadata := fbEncode(fbTable{fbI16(arrowVersion), fbU8(headerType), fbRef(header), fbI64(int64(len(body)))})
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+
+	prefix := binary.LittleEndian.AppendUint32(nil, arrowContinuation)
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(len(metadata)))
+
+	for _, data := range [][]byte{prefix, metadata, body} {
+		if _, err := a.writer.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// arrowField returns the Field table of a column. Fields are nullable, as
+// Arrow libraries make them by default.
+func arrowField(column Column) fbTable {
+	field := func(name string, typeID uint8, typ fbTable, children ...fbObject) fbTable {
+		// Name, nullable, type type, type, dictionary, children.
+		return fbTable{fbRef(fbString(name)), fbBool(true), fbU8(typeID), fbRef(typ), {}, fbRef(fbVector(children))}
+	}
+
+	switch column.Type {
+	case TypeInt:
+		// Bit width, signed.
+		return field(column.Name, arrowInt, fbTable{fbI32(64), fbBool(true)})
+	case TypeStrings:
+		return field(column.Name, arrowList, fbTable{}, field("item", arrowUtf8, fbTable{}))
+	case TypeFloats:
+		return field(column.Name, arrowList, fbTable{}, field("item", arrowFloatingPoint, fbTable{fbI16(arrowSingle)}))
+	}
+
+	return field(column.Name, arrowUtf8, fbTable{})
+}
+
+// arrowBatchWriter collects the field nodes, buffers and body of a record
+// batch. Columns are added in schema order, children after their parent.
+type arrowBatchWriter struct {
+	nodes, buffers, body []byte
+}
+
+func (b *arrowBatchWriter) node(length, nulls int) {
+	b.nodes = binary.LittleEndian.AppendUint64(b.nodes, uint64(length))
+	b.nodes = binary.LittleEndian.AppendUint64(b.nodes, uint64(nulls))
+}
+
+// buffer appends data to the body, padded to 8 bytes. An empty validity
+// buffer means no value is null.
+func (b *arrowBatchWriter) buffer(data []byte) {
+	b.buffers = binary.LittleEndian.AppendUint64(b.buffers, uint64(len(b.body)))
+	b.buffers = binary.LittleEndian.AppendUint64(b.buffers, uint64(len(data)))
+
+	b.body = append(b.body, data...)
+	for len(b.body)%8 != 0 {
+		b.body = append(b.body, 0)
+	}
+}
+
+// strings adds a Utf8 array of values.
+func (b *arrowBatchWriter) strings(values []string) {
+	offsets := binary.LittleEndian.AppendUint32(nil, 0)
+	var data []byte
+	for _, value := range values {
+		data = append(data, value...)
+		offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+	}
+
+	b.node(len(values), 0)
+	b.buffer(nil)
+	b.buffer(offsets)
+	b.buffer(data)
+}
+
+func (b *arrowBatchWriter) column(typ string, rows [][]interface{}, i int) {
+	switch typ {
+	case TypeString:
+		values := make([]string, len(rows))
+		for r, row := range rows {
+			values[r] = row[i].(string)
+		}
+		b.strings(values)
+
+	case TypeInt:
+		var data []byte
+		for _, row := range rows {
+			data = binary.LittleEndian.AppendUint64(data, uint64(int64(row[i].(int))))
+		}
+		b.node(len(rows), 0)
+		b.buffer(nil)
+		b.buffer(data)
+
+	case TypeStrings:
+		offsets := binary.LittleEndian.AppendUint32(nil, 0)
+		var items []string
+		for _, row := range rows {
+			items = append(items, row[i].([]string)...)
+			offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(items)))
+		}
+		b.node(len(rows), 0)
+		b.buffer(nil)
+		b.buffer(offsets)
+		b.strings(items)
+
+	case TypeFloats:
+		var (
+			offsets  = binary.LittleEndian.AppendUint32(nil, 0)
+			validity = make([]byte, (len(rows)+7)/8)
+			data     []byte
+			items    int
+			nulls    int
+		)
+		for r, row := range rows {
+			values := row[i].([]float32)
+			if values == nil {
+				nulls++
+			} else {
+				validity[r/8] |= 1 << (r % 8)
+			}
+			for _, v := range values {
+				data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+			}
+			items += len(values)
+			offsets = binary.LittleEndian.AppendUint32(offsets, uint32(items))
+		}
+		if nulls == 0 {
+			validity = nil
+		}
+		b.node(len(rows), nulls)
+		b.buffer(validity)
+		b.buffer(offsets)
+		b.node(items, 0)
+		b.buffer(nil)
+		b.buffer(data)
+	}
+}
+
+// arrowType is a field of an Arrow schema.
+type arrowType struct {
+	name     string
+	id       uint8
+	bits     int // Int bit width, FloatingPoint 32 or 64
+	signed   bool
+	size     int // FixedSizeList list size
+	children []arrowType
+}
+
+// arrowReader reads the rows of an Arrow IPC stream. Lines are numbered by
+// row across record batches.
+type arrowReader struct {
+	reader  io.Reader
+	fields  []arrowType
+	columns [][]interface{}
+	row     int
+	rows    int
+	line    int
+}
+
+func newArrowReader(r io.Reader) (*arrowReader, error) {
+	a := &arrowReader{reader: r}
+
+	headerType, header, _, err := a.message()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing Arrow schema")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if headerType != arrowSchema {
+		return nil, fmt.Errorf("Arrow stream must start with a schema")
+	}
+
+	err = fbDecode(header, func(message fbTab) error {
+		schema, _ := message.table(2)
+		if schema.i16(0) != 0 {
+			return fmt.Errorf("big-endian Arrow data is not supported")
+		}
+
+		for _, field := range schema.tables(1) {
+			typ, err := readArrowType(field)
+			if err != nil {
+				return err
+			}
+			a.fields = append(a.fields, typ)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Arrow schema: %w", err)
+	}
+
+	return a, nil
+}
+
+func readArrowType(field fbTab) (arrowType, error) {
+	t := arrowType{name: field.str(0), id: field.u8(2)}
+	if field.has(4) {
+		return t, fmt.Errorf("field %s: dictionary encoding is not supported", t.name)
+	}
+
+	typ, _ := field.table(3)
+	switch t.id {
+	case arrowInt:
+		t.bits, t.signed = int(typ.i32(0)), typ.bool(1)
+		if t.bits != 8 && t.bits != 16 && t.bits != 32 && t.bits != 64 {
+			return t, fmt.Errorf("field %s: invalid Int bit width %d", t.name, t.bits)
+		}
+	case arrowFloatingPoint:
+		switch typ.i16(0) {
+		case arrowSingle:
+			t.bits = 32
+		case arrowDouble:
+			t.bits = 64
+		default:
+			return t, fmt.Errorf("field %s: only single and double floats are supported", t.name)
+		}
+	case arrowFixedSizeList:
+		t.size = int(typ.i32(0))
+		if t.size < 0 {
+			return t, fmt.Errorf("field %s: invalid list size %d", t.name, t.size)
+		}
+	case arrowUtf8, arrowLargeUtf8, arrowBool, arrowList:
+	default:
+		return t, fmt.Errorf("field %s: Arrow type %d is not supported", t.name, t.id)
+	}
+
+	for _, child := range field.tables(5) {
+		c, err := readArrowType(child)
+		if err != nil {
+			return t, err
+		}
+		t.children = append(t.children, c)
+	}
+
+	if (t.id == arrowList || t.id == arrowFixedSizeList) != (len(t.children) == 1) {
+		return t, fmt.Errorf("field %s: unexpected children", t.name)
+	}
+
+	return t, nil
+}
+
+// message reads the next encapsulated message and its body. It returns
+// io.EOF at the end-of-stream marker or the end of the input.
+func (a *arrowReader) message() (uint8, []byte, []byte, error) {
+	var word [4]byte
+
+	if _, err := io.ReadFull(a.reader, word[:]); err != nil {
+		return 0, nil, nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(word[:])
+	if length == arrowContinuation {
+		if _, err := io.ReadFull(a.reader, word[:]); err != nil {
+			return 0, nil, nil, unexpectedEOF(err)
+		}
+		length = binary.LittleEndian.Uint32(word[:])
+	}
+	if length == 0 {
+		return 0, nil, nil, io.EOF
+	}
+	if length > maxLine {
+		return 0, nil, nil, fmt.Errorf("Arrow message of %d bytes is too large", length)
+	}
+
+	metadata := make([]byte, length)
+	if _, err := io.ReadFull(a.reader, metadata); err != nil {
+		return 0, nil, nil, unexpectedEOF(err)
+	}
+
+	var (
+		headerType uint8
+		bodyLength int64
+	)
+	err := fbDecode(metadata, func(message fbTab) error {
+		if version := message.i16(0); version < 3 {
+			return fmt.Errorf("Arrow metadata version %d is not supported", version+1)
+		}
+		headerType, bodyLength = message.u8(1), message.i64(3)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("Arrow message: %w", err)
+	}
+	if bodyLength < 0 || bodyLength > arrowMaxBody {
+		return 0, nil, nil, fmt.Errorf("Arrow record batch of %d bytes is too large", bodyLength)
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(a.reader, body); err != nil {
+		return 0, nil, nil, unexpectedEOF(err)
+	}
+
+	return headerType, metadata, body, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (a *arrowReader) Read() (Record, error) {
+	for a.row >= a.rows {
+		headerType, metadata, body, err := a.message()
+		if err != nil {
+			return Record{}, err
+		}
+
+		switch headerType {
+		case arrowRecordBatch:
+		case arrowDictionaryBatch:
+			return Record{}, fmt.Errorf("dictionary-encoded Arrow data is not supported")
+		default:
+			// Arrow allows other messages between batches; none carries rows.
+			continue
+		}
+
+		err = fbDecode(metadata, func(message fbTab) error {
+			batch, _ := message.table(2)
+			return a.batch(batch, body)
+		})
+		if err != nil {
+			return Record{}, fmt.Errorf("Arrow record batch: %w", err)
+		}
+	}
+
+	fields := make(map[string]interface{}, len(a.fields))
+	for i, field := range a.fields {
+		fields[field.name] = a.columns[i][a.row]
+	}
+
+	a.row++
+	a.line++
+
+	return Record{Line: a.line, Fields: fields}, nil
+}
+
+// batch decodes the columns of a record batch.
+func (a *arrowReader) batch(batch fbTab, body []byte) error {
+	if batch.has(3) {
+		return errors.New("compressed Arrow data is not supported")
+	}
+
+	length := batch.i64(0)
+	if length < 0 || length > int64(len(body))*8+arrowBatchRows {
+		return errCorrupt
+	}
+
+	d := &arrowBatchReader{meta: batch.buf, body: body}
+	d.nodes, d.nodeCount = batch.vector(1, 16)
+	d.buffers, d.bufferCount = batch.vector(2, 16)
+
+	a.columns = make([][]interface{}, len(a.fields))
+	for i, field := range a.fields {
+		values := d.column(field)
+		if len(values) != int(length) {
+			return errCorrupt
+		}
+		a.columns[i] = values
+	}
+
+	a.row, a.rows = 0, int(length)
+	return nil
+}
+
+// arrowBatchReader walks the field nodes and buffers of a record batch.
+// Malformed input raises errCorrupt, which fbDecode recovers.
+type arrowBatchReader struct {
+	meta, body             []byte
+	nodes, nodeCount, node int
+	buffers, bufferCount   int
+	buffer                 int
+}
+
+func (d *arrowBatchReader) nextNode() (int, int) {
+	if d.node >= d.nodeCount {
+		panic(errCorrupt)
+	}
+	data := fbBytes(d.meta, d.nodes+16*d.node, 16)
+	d.node++
+
+	length, nulls := int64(binary.LittleEndian.Uint64(data)), int64(binary.LittleEndian.Uint64(data[8:]))
+	if length < 0 || nulls < 0 || nulls > length || length > int64(len(d.body))*8+arrowBatchRows {
+		panic(errCorrupt)
+	}
+
+	return int(length), int(nulls)
+}
+
+func (d *arrowBatchReader) nextBuffer() []byte {
+	if d.buffer >= d.bufferCount {
+		panic(errCorrupt)
+	}
+	data := fbBytes(d.meta, d.buffers+16*d.buffer, 16)
+	d.buffer++
+
+	offset, length := int64(binary.LittleEndian.Uint64(data)), int64(binary.LittleEndian.Uint64(data[8:]))
+	if offset < 0 || length < 0 || offset > int64(len(d.body)) || length > int64(len(d.body))-offset {
+		panic(errCorrupt)
+	}
+
+	return d.body[offset : offset+length]
+}
+
+// validity returns whether value i is null for a node.
+func (d *arrowBatchReader) validity(length, nulls int) func(i int) bool {
+	bitmap := d.nextBuffer()
+	if nulls == 0 || len(bitmap) == 0 {
+		return func(int) bool { return false }
+	}
+	fbBytes(bitmap, 0, (length+7)/8)
+
+	return func(i int) bool { return bitmap[i/8]&(1<<(i%8)) == 0 }
+}
+
+// offsets returns the start and end of list or string i.
+func (d *arrowBatchReader) offsets(t arrowType, length int) func(i int) (int, int) {
+	if t.id == arrowFixedSizeList {
+		return func(i int) (int, int) { return i * t.size, (i + 1) * t.size }
+	}
+
+	width := 4
+	if t.id == arrowLargeUtf8 {
+		width = 8
+	}
+
+	data := d.nextBuffer()
+	if length > 0 {
+		fbBytes(data, 0, (length+1)*width)
+	}
+
+	at := func(i int) int {
+		if width == 8 {
+			return int(int64(binary.LittleEndian.Uint64(data[8*i:])))
+		}
+		return int(int32(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+
+	return func(i int) (int, int) {
+		start, end := at(i), at(i+1)
+		if start < 0 || end < start {
+			panic(errCorrupt)
+		}
+		return start, end
+	}
+}
+
+// column decodes the array of a field: strings, bools, json.Number for
+// numbers, []float32 for lists of floats and []interface{} for other lists.
+// Nulls are nil.
+func (d *arrowBatchReader) column(t arrowType) []interface{} {
+	length, nulls := d.nextNode()
+	null := d.validity(length, nulls)
+	values := make([]interface{}, length)
+
+	switch t.id {
+	case arrowInt:
+		data := fbBytes(d.nextBuffer(), 0, length*t.bits/8)
+		for i := range values {
+			if null(i) {
+				continue
+			}
+			values[i] = json.Number(arrowInteger(t, data, i))
+		}
+
+	case arrowFloatingPoint:
+		data := fbBytes(d.nextBuffer(), 0, length*t.bits/8)
+		for i := range values {
+			if null(i) {
+				continue
+			}
+			values[i] = json.Number(strconv.FormatFloat(arrowFloat(t, data, i), 'g', -1, t.bits))
+		}
+
+	case arrowBool:
+		data := fbBytes(d.nextBuffer(), 0, (length+7)/8)
+		for i := range values {
+			if null(i) {
+				continue
+			}
+			values[i] = data[i/8]&(1<<(i%8)) != 0
+		}
+
+	case arrowUtf8, arrowLargeUtf8:
+		offsets := d.offsets(t, length)
+		data := d.nextBuffer()
+		for i := range values {
+			if null(i) {
+				continue
+			}
+			start, end := offsets(i)
+			values[i] = string(fbBytes(data, start, end-start))
+		}
+
+	case arrowList, arrowFixedSizeList:
+		offsets := d.offsets(t, length)
+		child := t.children[0]
+
+		var (
+			floats []float32
+			items  []interface{}
+			count  int
+		)
+		if child.id == arrowFloatingPoint {
+			floats = d.floats(child)
+			count = len(floats)
+		} else {
+			items = d.column(child)
+			count = len(items)
+		}
+
+		for i := range values {
+			if null(i) {
+				continue
+			}
+			start, end := offsets(i)
+			if end > count {
+				panic(errCorrupt)
+			}
+			if floats != nil {
+				values[i] = floats[start:end:end]
+			} else {
+				values[i] = items[start:end:end]
+			}
+		}
+	}
+
+	return values
+}
+
+// floats decodes a float array as float32, with nulls as NaN.
+func (d *arrowBatchReader) floats(t arrowType) []float32 {
+	length, nulls := d.nextNode()
+	null := d.validity(length, nulls)
+	data := fbBytes(d.nextBuffer(), 0, length*t.bits/8)
+
+	values := make([]float32, length)
+	for i := range values {
+		if null(i) {
+			values[i] = float32(math.NaN())
+			continue
+		}
+		values[i] = float32(arrowFloat(t, data, i))
+	}
+
+	return values
+}
+
+func arrowInteger(t arrowType, data []byte, i int) string {
+	var v uint64
+	switch t.bits {
+	case 8:
+		v = uint64(data[i])
+		if t.signed {
+			return strconv.FormatInt(int64(int8(v)), 10)
+		}
+	case 16:
+		v = uint64(binary.LittleEndian.Uint16(data[2*i:]))
+		if t.signed {
+			return strconv.FormatInt(int64(int16(v)), 10)
+		}
+	case 32:
+		v = uint64(binary.LittleEndian.Uint32(data[4*i:]))
+		if t.signed {
+			return strconv.FormatInt(int64(int32(v)), 10)
+		}
+	default:
+		v = binary.LittleEndian.Uint64(data[8*i:])
+		if t.signed {
+			return strconv.FormatInt(int64(v), 10)
+		}
+	}
+
+	return strconv.FormatUint(v, 10)
+}
+
+func arrowFloat(t arrowType, data []byte, i int) float64 {
+	if t.bits == 32 {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+}
@@ -0,0 +1,308 @@
+package records
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// This file holds the little of FlatBuffers the Arrow IPC metadata needs:
+// an encoder that lays objects out front to back, and bounds-checked
+// accessors for reading them.
+
+// fbObject is a table, vector or string that is referenced by offset.
+type fbObject interface {
+	place(b *fbBuilder) int
+}
+
+// fbSlot is a table field: a little-endian scalar, a reference to an
+// object, or absent when both are empty.
+type fbSlot struct {
+	scalar []byte
+	ref    fbObject
+}
+
+func (s fbSlot) width() int {
+	if s.ref != nil {
+		return 4
+	}
+	return len(s.scalar)
+}
+
+func fbU8(v uint8) fbSlot { return fbSlot{scalar: []byte{v}} }
+
+func fbBool(v bool) fbSlot {
+	if v {
+		return fbU8(1)
+	}
+	return fbU8(0)
+}
+
+func fbI16(v int16) fbSlot {
+	return fbSlot{scalar: binary.LittleEndian.AppendUint16(nil, uint16(v))}
+}
+
+func fbI32(v int32) fbSlot {
+	return fbSlot{scalar: binary.LittleEndian.AppendUint32(nil, uint32(v))}
+}
+
+func fbI64(v int64) fbSlot {
+	return fbSlot{scalar: binary.LittleEndian.AppendUint64(nil, uint64(v))}
+}
+
+func fbRef(o fbObject) fbSlot { return fbSlot{ref: o} }
+
+// fbTable is a table whose fields are given by id.
+type fbTable []fbSlot
+
+// fbVector is a vector of references.
+type fbVector []fbObject
+
+// fbString is a string.
+type fbString string
+
+// fbStructs is a vector of count structs of 8-byte aligned fields.
+type fbStructs struct {
+	count int
+	data  []byte
+}
+
+type fbBuilder struct {
+	buf []byte
+}
+
+// fbEncode returns a buffer whose root is the given table.
+func fbEncode(root fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	binary.LittleEndian.PutUint32(b.buf, uint32(root.place(b)))
+
+	return b.buf
+}
+
+// align pads the buffer until len(buf)+extra is a multiple of n.
+func (b *fbBuilder) align(n, extra int) {
+	for (len(b.buf)+extra)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// ref stores the offset from at to the object placed next.
+func (b *fbBuilder) ref(at int, o fbObject) {
+	target := o.place(b)
+	binary.LittleEndian.PutUint32(b.buf[at:], uint32(target-at))
+}
+
+// place writes the vtable, then the table, 8-byte aligned with its fields
+// sorted by size so each is aligned, then the objects it references.
+func (t fbTable) place(b *fbBuilder) int {
+	order := make([]int, len(t))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return t[order[i]].width() > t[order[j]].width() })
+
+	offsets := make([]int, len(t))
+	size := 4
+	for _, i := range order {
+		w := t[i].width()
+		if w == 0 {
+			continue
+		}
+		for size%w != 0 {
+			size++
+		}
+		offsets[i] = size
+		size += w
+	}
+
+	vtableSize := 4 + 2*len(t)
+	b.align(8, vtableSize)
+
+	vtable := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(vtableSize))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(size))
+	for _, offset := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(offset))
+	}
+
+	start := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(start-vtable))
+
+	for i, slot := range t {
+		if slot.scalar != nil {
+			copy(b.buf[start+offsets[i]:], slot.scalar)
+		}
+	}
+	for i, slot := range t {
+		if slot.ref != nil {
+			b.ref(start+offsets[i], slot.ref)
+		}
+	}
+
+	return start
+}
+
+func (v fbVector) place(b *fbBuilder) int {
+	b.align(4, 0)
+
+	start := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+	b.buf = append(b.buf, make([]byte, 4*len(v))...)
+
+	for i, o := range v {
+		b.ref(start+4+4*i, o)
+	}
+
+	return start
+}
+
+func (s fbString) place(b *fbBuilder) int {
+	b.align(4, 0)
+
+	start := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+
+	return start
+}
+
+func (s fbStructs) place(b *fbBuilder) int {
+	b.align(8, 4)
+
+	start := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(s.count))
+	b.buf = append(b.buf, s.data...)
+
+	return start
+}
+
+// errCorrupt is raised by the accessors below when an offset points outside
+// the buffer; fbDecode turns it into an error.
+var errCorrupt = errors.New("corrupt metadata")
+
+type fbTab struct {
+	buf []byte
+	pos int
+}
+
+// fbDecode calls fn with the root table of buf, reporting out of bounds
+// reads as errCorrupt.
+func fbDecode(buf []byte, fn func(root fbTab) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != errCorrupt { //nolint:errorlint
+				panic(p)
+			}
+			err = errCorrupt
+		}
+	}()
+
+	return fn(fbTab{buf: buf, pos: int(fbU32(buf, 0))})
+}
+
+func fbBytes(buf []byte, pos, n int) []byte {
+	if pos < 0 || n < 0 || pos > len(buf) || n > len(buf)-pos {
+		panic(errCorrupt)
+	}
+	return buf[pos : pos+n]
+}
+
+func fbU32(buf []byte, pos int) uint32 {
+	return binary.LittleEndian.Uint32(fbBytes(buf, pos, 4))
+}
+
+// field returns the position of field id, or 0 when it is absent.
+func (t fbTab) field(id int) int {
+	vtable := t.pos - int(int32(fbU32(t.buf, t.pos)))
+	size := int(binary.LittleEndian.Uint16(fbBytes(t.buf, vtable, 2)))
+
+	at := 4 + 2*id
+	if at+2 > size {
+		return 0
+	}
+
+	offset := int(binary.LittleEndian.Uint16(fbBytes(t.buf, vtable+at, 2)))
+	if offset == 0 {
+		return 0
+	}
+
+	return t.pos + offset
+}
+
+func (t fbTab) uint(id, width int) uint64 {
+	pos := t.field(id)
+	if pos == 0 {
+		return 0
+	}
+
+	data := fbBytes(t.buf, pos, width)
+	switch width {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(data))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(data))
+	}
+	return binary.LittleEndian.Uint64(data)
+}
+
+func (t fbTab) u8(id int) uint8   { return uint8(t.uint(id, 1)) }
+func (t fbTab) i16(id int) int16  { return int16(t.uint(id, 2)) }
+func (t fbTab) i32(id int) int32  { return int32(t.uint(id, 4)) }
+func (t fbTab) i64(id int) int64  { return int64(t.uint(id, 8)) }
+func (t fbTab) bool(id int) bool  { return t.uint(id, 1) != 0 }
+func (t fbTab) has(id int) bool   { return t.field(id) != 0 }
+func (t fbTab) deref(pos int) int { return pos + int(fbU32(t.buf, pos)) }
+
+// table returns the table referenced by field id.
+func (t fbTab) table(id int) (fbTab, bool) {
+	pos := t.field(id)
+	if pos == 0 {
+		return fbTab{}, false
+	}
+
+	return fbTab{buf: t.buf, pos: t.deref(pos)}, true
+}
+
+func (t fbTab) str(id int) string {
+	pos := t.field(id)
+	if pos == 0 {
+		return ""
+	}
+
+	start := t.deref(pos)
+	return string(fbBytes(t.buf, start+4, int(fbU32(t.buf, start))))
+}
+
+// vector returns the position of the first element and the length of the
+// vector of elements of size width referenced by field id.
+func (t fbTab) vector(id, width int) (int, int) {
+	pos := t.field(id)
+	if pos == 0 {
+		return 0, 0
+	}
+
+	start := t.deref(pos)
+	n := int(fbU32(t.buf, start))
+	if n > len(t.buf)/width {
+		panic(errCorrupt)
+	}
+	fbBytes(t.buf, start+4, n*width)
+
+	return start + 4, n
+}
+
+// tables returns the tables of the vector referenced by field id.
+func (t fbTab) tables(id int) []fbTab {
+	start, n := t.vector(id, 4)
+
+	tables := make([]fbTab, n)
+	for i := range tables {
+		tables[i] = fbTab{buf: t.buf, pos: t.deref(start + 4*i)}
+	}
+
+	return tables
+}
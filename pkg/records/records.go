@@ -0,0 +1,231 @@
+// Package records reads uploads of flat records one at a time from CSV,
+// JSON Lines, JSON array or Arrow IPC streams, and writes records as CSV,
+// JSON Lines or Arrow IPC streams.
+package records
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats accepted by NewReader.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+	FormatArrow  = "arrow"
+)
+
+// maxLine is the longest JSON line accepted.
+const maxLine = 4 << 20
+
+// Record is one upload entry keyed by column name. CSV values are strings;
+// JSON values are whatever encoding/json decodes, with numbers kept as
+// json.Number. Arrow values are decoded the same way, except that lists of
+// floats come as []float32.
+type Record struct {
+	Line   int
+	Fields map[string]interface{}
+}
+
+// LineError reports an entry that could not be decoded. Reading can go on
+// with the next entry.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Reader returns records until io.EOF. Errors other than *LineError end the
+// stream.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// Format maps a format name or a Content-Type to one of the formats, or ""
+// when it is not recognised.
+func Format(s string) string {
+	s = strings.ToLower(strings.TrimSpace(strings.Split(s, ";")[0]))
+
+	switch s {
+	case FormatCSV, "text/csv", "application/csv":
+		return FormatCSV
+	case FormatNDJSON, "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return FormatNDJSON
+	case FormatJSON, "application/json":
+		return FormatJSON
+	case FormatArrow, "arrows", "application/vnd.apache.arrow.stream":
+		return FormatArrow
+	}
+
+	return ""
+}
+
+// NewReader returns a reader for r in the given format. CSV input must
+// start with a header row naming the columns.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLine)
+		return &ndjsonReader{scanner: scanner}, nil
+	case FormatJSON:
+		return newJSONReader(r)
+	case FormatArrow:
+		return newArrowReader(r)
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	reader.FieldsPerRecord = len(header)
+	return &csvReader{reader: reader, header: header}, nil
+}
+
+func (c *csvReader) Read() (Record, error) {
+	values, err := c.reader.Read()
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{}, &LineError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+
+	fields := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		fields[c.header[i]] = value
+	}
+
+	return Record{Line: line, Fields: fields}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonReader) Read() (Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+
+		text := bytes.TrimSpace(n.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		fields, err := decodeObject(text)
+		if err != nil {
+			return Record{}, &LineError{Line: n.line, Err: err}
+		}
+
+		return Record{Line: n.line, Fields: fields}, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+
+	return Record{}, io.EOF
+}
+
+// jsonReader streams the elements of a top-level JSON array. Records are
+// numbered by their position in the array.
+type jsonReader struct {
+	decoder *json.Decoder
+	index   int
+}
+
+func newJSONReader(r io.Reader) (*jsonReader, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("JSON input must be an array of objects")
+	}
+
+	return &jsonReader{decoder: decoder}, nil
+}
+
+func (j *jsonReader) Read() (Record, error) {
+	if !j.decoder.More() {
+		return Record{}, io.EOF
+	}
+	j.index++
+
+	var raw json.RawMessage
+	if err := j.decoder.Decode(&raw); err != nil {
+		// The position in the stream is lost, so the upload cannot go on.
+		return Record{}, fmt.Errorf("element %d: %w", j.index, err)
+	}
+
+	fields, err := decodeObject(raw)
+	if err != nil {
+		return Record{}, &LineError{Line: j.index, Err: err}
+	}
+
+	return Record{Line: j.index, Fields: fields}, nil
+}
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,279 @@
+package records
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testColumns = []Column{
+	{Name: "name", Type: TypeString},
+	{Name: "year", Type: TypeInt},
+	{Name: "aliases", Type: TypeStrings},
+	{Name: "embedding", Type: TypeFloats},
+}
+
+// testRow is a record of testColumns as it is written.
+type testRow struct {
+	name      string
+	year      int
+	aliases   []string
+	embedding []float32
+}
+
+func (r testRow) values() []interface{} {
+	return []interface{}{r.name, r.year, r.aliases, r.embedding}
+}
+
+// readRow converts a record read back to a testRow, whatever the format
+// decoded its values to.
+func readRow(t *testing.T, fields map[string]interface{}) testRow {
+	t.Helper()
+
+	var row testRow
+
+	row.name, _ = fields["name"].(string)
+
+	var year json.Number
+	switch v := fields["year"].(type) {
+	case json.Number:
+		year = v
+	case string:
+		year = json.Number(v)
+	}
+	n, err := year.Int64()
+	if err != nil {
+		t.Fatalf("year %#v is not an integer", fields["year"])
+	}
+	row.year = int(n)
+
+	switch v := fields["aliases"].(type) {
+	case string:
+		if v != "" {
+			row.aliases = strings.Split(v, "|")
+		}
+	case []interface{}:
+		for _, alias := range v {
+			row.aliases = append(row.aliases, alias.(string))
+		}
+	}
+
+	switch v := fields["embedding"].(type) {
+	case []float32:
+		row.embedding = v
+	case string:
+		if v != "" {
+			if err := json.Unmarshal([]byte(v), &row.embedding); err != nil {
+				t.Fatalf("embedding %q: %v", v, err)
+			}
+		}
+	case []interface{}:
+		for _, x := range v {
+			f, err := x.(json.Number).Float64()
+			if err != nil {
+				t.Fatalf("embedding value %#v: %v", x, err)
+			}
+			row.embedding = append(row.embedding, float32(f))
+		}
+	}
+
+	return row
+}
+
+func roundTrip(t *testing.T, format string, rows []testRow) []testRow {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, format, testColumns)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, row := range rows {
+		if err = w.Write(row.values()); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	r, err := NewReader(&buf, format)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	var got []testRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		got = append(got, readRow(t, record.Fields))
+	}
+
+	return got
+}
+
+func TestRoundTrip(t *testing.T) {
+	rows := []testRow{
+		{name: "Alpha", year: 1999, aliases: []string{"The Alpha", "Альфа"}, embedding: []float32{0.5, -1.25, 3.0e-7}},
+		{name: `Comma, "quote"` + "\nnewline", year: 0},
+		{name: "O'zbek kino", year: 2024, aliases: []string{"single"}, embedding: []float32{0}},
+	}
+
+	for _, format := range []string{FormatCSV, FormatNDJSON, FormatArrow} {
+		t.Run(format, func(t *testing.T) {
+			got := roundTrip(t, format, rows)
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("read back %+v, want %+v", got, rows)
+			}
+		})
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	for _, format := range []string{FormatNDJSON, FormatArrow} {
+		t.Run(format, func(t *testing.T) {
+			if got := roundTrip(t, format, nil); len(got) != 0 {
+				t.Errorf("read back %d records, want none", len(got))
+			}
+		})
+	}
+}
+
+// TestArrowBatches writes more rows than fit in one record batch.
+func TestArrowBatches(t *testing.T) {
+	rows := make([]testRow, arrowBatchRows*2+3)
+	for i := range rows {
+		rows[i] = testRow{
+			name:      fmt.Sprintf("movie %d", i),
+			year:      1900 + i%120,
+			embedding: []float32{float32(i), float32(-i) / 3},
+		}
+		if i%2 == 0 {
+			rows[i].aliases = []string{fmt.Sprintf("alias %d", i)}
+		}
+	}
+
+	got := roundTrip(t, FormatArrow, rows)
+	if len(got) != len(rows) {
+		t.Fatalf("read back %d records, want %d", len(got), len(rows))
+	}
+	for i := range rows {
+		if !reflect.DeepEqual(got[i], rows[i]) {
+			t.Fatalf("record %d = %+v, want %+v", i, got[i], rows[i])
+		}
+	}
+}
+
+func TestReadJSONArray(t *testing.T) {
+	r, err := NewReader(strings.NewReader(`[{"name": "a", "year": 1}, {"name": "b", "year": 2.5}]`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		lines = append(lines, record.Line)
+		if _, ok := record.Fields["year"].(json.Number); !ok {
+			t.Errorf("year is %T, want json.Number", record.Fields["year"])
+		}
+	}
+
+	if !reflect.DeepEqual(lines, []int{1, 2}) {
+		t.Errorf("lines = %v, want [1 2]", lines)
+	}
+}
+
+// TestLineErrors checks that a bad entry is reported with its line and
+// reading goes on with the next one.
+func TestLineErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		line   int
+	}{
+		{FormatNDJSON, "{\"name\": \"a\"}\n{broken\n{\"name\": \"c\"}\n", 2},
+		{FormatNDJSON, "{\"name\": \"a\"}\n[1, 2]\n{\"name\": \"c\"}\n", 2},
+		{FormatCSV, "name,year\na,1\nb\nc,3\n", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				names   []string
+				failure *LineError
+			)
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				if errors.As(err, &failure) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+				names = append(names, record.Fields["name"].(string))
+			}
+
+			if failure == nil || failure.Line != tt.line {
+				t.Errorf("line error = %v, want one on line %d", failure, tt.line)
+			}
+			if !reflect.DeepEqual(names, []string{"a", "c"}) {
+				t.Errorf("read %v, want [a c]", names)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"csv":                                 FormatCSV,
+		"text/csv; charset=utf-8":             FormatCSV,
+		"application/x-ndjson":                FormatNDJSON,
+		"JSONL":                               FormatNDJSON,
+		"application/json":                    FormatJSON,
+		"application/vnd.apache.arrow.stream": FormatArrow,
+		"application/parquet":                 "",
+	}
+
+	for input, want := range tests {
+		if got := Format(input); got != want {
+			t.Errorf("Format(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestNewWriterRejectsUnknownColumnType(t *testing.T) {
+	var buf bytes.Buffer
+
+	if _, err := NewWriter(&buf, FormatCSV, []Column{{Name: "x", Type: "decimal"}}); err == nil {
+		t.Error("NewWriter() accepted an unknown column type")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("NewWriter() wrote %q", buf.String())
+	}
+}
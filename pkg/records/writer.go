@@ -0,0 +1,178 @@
+package records
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Types of the values a Writer takes for a column.
+const (
+	TypeString  = "string"  // string
+	TypeInt     = "int"     // int
+	TypeStrings = "strings" // []string
+	TypeFloats  = "floats"  // []float32; nil is written as null
+)
+
+// Column names a column of a Writer and the type of its values.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Writer writes records whose values are given in column order. Close must
+// be called after the last record; it does not close the underlying writer.
+type Writer interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// NewWriter returns a writer of the given format to w. Nothing is written
+// before the first record or Close, so an error from NewWriter leaves w
+// untouched. CSV output starts with a header row; string lists are joined
+// with "|" and float lists are written as "[x,y,...]", both of which
+// NewReader reads back.
+func NewWriter(w io.Writer, format string, columns []Column) (Writer, error) {
+	for _, column := range columns {
+		switch column.Type {
+		case TypeString, TypeInt, TypeStrings, TypeFloats:
+		default:
+			return nil, fmt.Errorf("column %s has unknown type %q", column.Name, column.Type)
+		}
+	}
+
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w), columns: columns}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{writer: bufio.NewWriter(w), columns: columns}, nil
+	case FormatArrow:
+		return newArrowWriter(w, columns), nil
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvWriter struct {
+	writer  *csv.Writer
+	columns []Column
+	started bool
+}
+
+func (c *csvWriter) header() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+
+	names := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		names[i] = column.Name
+	}
+
+	return c.writer.Write(names)
+}
+
+func (c *csvWriter) Write(values []interface{}) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	fields := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		switch v := values[i].(type) {
+		case string:
+			fields[i] = v
+		case int:
+			fields[i] = strconv.Itoa(v)
+		case []string:
+			fields[i] = strings.Join(v, "|")
+		case []float32:
+			if v != nil {
+				fields[i] = formatFloats(v)
+			}
+		default:
+			return fmt.Errorf("column %s: unexpected %T", column.Name, values[i])
+		}
+	}
+
+	return c.writer.Write(fields)
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonWriter struct {
+	writer  *bufio.Writer
+	columns []Column
+}
+
+func (n *ndjsonWriter) Write(values []interface{}) error {
+	n.writer.WriteByte('{')
+
+	for i, column := range n.columns {
+		if i > 0 {
+			n.writer.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(column.Name)
+		n.writer.Write(name)
+		n.writer.WriteByte(':')
+
+		value := values[i]
+		switch v := value.(type) {
+		case []string:
+			if v == nil {
+				value = []string{}
+			}
+		case []float32:
+			if v != nil {
+				n.writer.WriteString(formatFloats(v))
+				continue
+			}
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		n.writer.Write(data)
+	}
+
+	n.writer.WriteString("}\n")
+
+	// Errors of the underlying writer stick and come back from every call.
+	_, err := n.writer.Write(nil)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.writer.Flush()
+}
+
+// formatFloats writes a float list as a JSON array, with the shortest
+// representation that reads back as the same float32.
+func formatFloats(values []float32) string {
+	var b strings.Builder
+
+	b.WriteByte('[')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package rerank
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Local scores documents by token overlap with the query. It needs no
+// network access and is deterministic, which makes it a stand-in for the
+// remote reranker in tests and local development.
+type Local struct{}
+
+var _ Interface = (*Local)(nil)
+
+// NewLocal -.
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Name -.
+func (l *Local) Name() string {
+	return "local"
+}
+
+// Rerank -.
+func (l *Local) Rerank(ctx context.Context, query string, docs []Document) ([]float32, error) {
+	queryTokens := tokenize(query)
+	scores := make([]float32, len(docs))
+
+	if len(queryTokens) == 0 {
+		return scores, nil
+	}
+
+	for i, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		docTokens := tokenize(doc.Text)
+
+		matched := 0
+		for token := range queryTokens {
+			if _, ok := docTokens[token]; ok {
+				matched++
+			}
+		}
+
+		scores[i] = float32(matched) / float32(len(queryTokens))
+	}
+
+	return scores, nil
+}
+
+func tokenize(text string) map[string]struct{} {
+	tokens := map[string]struct{}{}
+
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[field] = struct{}{}
+	}
+
+	return tokens
+}
@@ -0,0 +1,76 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const _systemPrompt = `You rank search results. Given a user query and a numbered list of documents, ` +
+	`rate how well each document answers the query on a scale from 0 to 1. ` +
+	`Reply with a JSON object {"scores": [...]} holding exactly one number per document, in the input order.`
+
+// OpenAI asks a chat completion model, served by OpenAI or any API
+// compatible with it, to score the documents.
+type OpenAI struct {
+	client *openai.Client
+	model  string
+}
+
+var _ Interface = (*OpenAI)(nil)
+
+// NewOpenAI -.
+func NewOpenAI(client *openai.Client, model string) *OpenAI {
+	return &OpenAI{
+		client: client,
+		model:  model,
+	}
+}
+
+// Name -.
+func (o *OpenAI) Name() string {
+	return "openai:" + o.model
+}
+
+// Rerank -.
+func (o *OpenAI) Rerank(ctx context.Context, query string, docs []Document) ([]float32, error) {
+	var prompt strings.Builder
+
+	fmt.Fprintf(&prompt, "Query: %s\n\nDocuments:\n", query)
+	for i, doc := range docs {
+		fmt.Fprintf(&prompt, "%d. %s\n", i+1, doc.Text)
+	}
+
+	resp, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: o.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: _systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: prompt.String()},
+		},
+		Temperature:    0,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rerank - OpenAI - CreateChatCompletion: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("rerank - OpenAI - empty completion")
+	}
+
+	var result struct {
+		Scores []float32 `json:"scores"`
+	}
+	if err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return nil, fmt.Errorf("rerank - OpenAI - Unmarshal: %w", err)
+	}
+
+	if len(result.Scores) != len(docs) {
+		return nil, fmt.Errorf("rerank - OpenAI - got %d scores for %d documents", len(result.Scores), len(docs))
+	}
+
+	return result.Scores, nil
+}
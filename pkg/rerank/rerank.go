@@ -0,0 +1,19 @@
+// Package rerank implements re-ranking of search candidates.
+package rerank
+
+import "context"
+
+// Document -.
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Interface -.
+type Interface interface {
+	// Name identifies the reranker in logs and explain output.
+	Name() string
+	// Rerank returns one relevance score per document, aligned with docs.
+	// Higher scores are more relevant.
+	Rerank(ctx context.Context, query string, docs []Document) ([]float32, error)
+}
@@ -0,0 +1,90 @@
+// Package slug turns titles into URL-safe, transliterated slugs.
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make returns.
+const MaxLength = 80
+
+// cyrillic transliterates the Russian and Uzbek Cyrillic alphabets.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "j",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ў': "o", 'қ': "q", 'ғ': "g", 'ҳ': "h",
+}
+
+// special covers Latin letters that do not decompose into a base letter.
+var special = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'ł': "l", 'đ': "d", 'ı': "i", 'þ': "th",
+}
+
+// Make lowercases and transliterates s, replaces every run of other
+// characters with a single hyphen and trims the result to MaxLength.
+// Uzbek apostrophes (o‘, g‘) are dropped rather than turned into hyphens.
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range strings.ToLower(s) {
+		out, word := transliterate(r)
+		if !word {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if out == "" {
+			continue
+		}
+
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(out)
+	}
+
+	result := b.String()
+	if len(result) > MaxLength {
+		result = result[:MaxLength]
+		if i := strings.LastIndexByte(result, '-'); i > MaxLength/2 {
+			result = result[:i]
+		}
+		result = strings.TrimRight(result, "-")
+	}
+
+	return result
+}
+
+// transliterate returns the ASCII letters and digits r stands for and
+// whether r is part of a word at all; separators report false. Cyrillic is
+// looked up before decomposition, since й, ё and ў decompose into other letters.
+func transliterate(r rune) (string, bool) {
+	if r == '\'' || r == '‘' || r == '’' || r == 'ʻ' || r == 'ʼ' || r == '`' {
+		return "", true
+	}
+	if t, ok := cyrillic[r]; ok {
+		return t, true
+	}
+	// Accents typed as combining marks belong to the letter before them.
+	if unicode.Is(unicode.Mn, r) {
+		return "", true
+	}
+	if t, ok := special[r]; ok {
+		return t, true
+	}
+
+	var b strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if d < unicode.MaxASCII && (unicode.IsLetter(d) || unicode.IsDigit(d)) {
+			b.WriteRune(d)
+		}
+	}
+
+	return b.String(), b.Len() > 0
+}
@@ -0,0 +1,62 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"ascii title", "  Star Wars: Episode IV — A New Hope!  ", "star-wars-episode-iv-a-new-hope"},
+		{"digits", "100% Love", "100-love"},
+		{"uzbek latin apostrophes", "O‘zbekiston va G'alaba", "ozbekiston-va-galaba"},
+		{"uzbek modifier letter apostrophe", "Oʻtkan kunlar", "otkan-kunlar"},
+		{"uzbek cyrillic", "Ўзбекистон", "ozbekiston"},
+		{"uzbek cyrillic letters", "Қўрқув ва Ғалаба ҳақида", "qorquv-va-galaba-haqida"},
+		{"russian", "Крёстный отец", "kryostnyy-otets"},
+		{"hard and soft signs", "Подъезд Ночь", "podezd-noch"},
+		{"composed accents", "Amélie Poulain", "amelie-poulain"},
+		{"combining accents", "Ame\u0301lie Poulain", "amelie-poulain"},
+		{"letters without decomposition", "Straße Æon Ørsted", "strasse-aeon-orsted"},
+		{"only separators", " !!! — ", ""},
+		{"no latin equivalent", "日本", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.input); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeTruncates(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"at a word boundary", strings.Repeat("word ", 30), strings.Repeat("word-", 15) + "word"},
+		{"inside a long word", strings.Repeat("a", 100), strings.Repeat("a", MaxLength)},
+		{"without a late word boundary", "a " + strings.Repeat("b", 100), "a-" + strings.Repeat("b", MaxLength-2)},
+		{"multi-letter transliterations", strings.Repeat("щ", 40), strings.Repeat("sch", 40)[:MaxLength]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Make(tt.input)
+			if got != tt.want {
+				t.Errorf("Make() = %q, want %q", got, tt.want)
+			}
+			if len(got) > MaxLength || strings.HasSuffix(got, "-") {
+				t.Errorf("Make() = %q, longer than %d or ending in a hyphen", got, MaxLength)
+			}
+		})
+	}
+}
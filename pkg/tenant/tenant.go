@@ -0,0 +1,32 @@
+// Package tenant carries the tenant a request acts for through context.
+package tenant
+
+import "context"
+
+type ctxKey struct{}
+
+// WithID returns a copy of ctx that carries the tenant id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// ID returns the tenant id carried by ctx, or an empty string.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+type allKey struct{}
+
+// WithAll returns a copy of ctx for background work that acts for every
+// tenant, such as the job and embedding workers. Row-level security lets
+// its queries through as long as ctx carries no tenant id.
+func WithAll(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allKey{}, true)
+}
+
+// All reports whether ctx was returned by WithAll.
+func All(ctx context.Context) bool {
+	all, _ := ctx.Value(allKey{}).(bool)
+	return all
+}